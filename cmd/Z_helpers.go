@@ -57,10 +57,135 @@ func CompleteStorages(_ *cobra.Command, args []string, toComplete string) ([]str
 	return completions, cobra.ShellCompDirectiveNoFileComp
 }
 
+// CompleteEntryPaths provides completion for the path of an entry in the storage. Nothing is completed if the storage
+// cannot list its entries or must be unlocked first.
+func CompleteEntryPaths(storageName string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	initConfig()
+	storagePtr, err := secretsStorage.GetRegistry().GetStorage(storageName)
+	if err != nil {
+		return []string{}, cobra.ShellCompDirectiveNoFileComp
+	}
+	entries, err := secretsStorage.ListEntriesWithoutPrompt(*storagePtr, toComplete)
+	if err != nil {
+		return []string{}, cobra.ShellCompDirectiveNoFileComp
+	}
+	return entries, cobra.ShellCompDirectiveNoFileComp
+}
+
+// listEntries lists the entries of the storage starting with prefix
+func listEntries(storageName string, prefix string) ([]string, error) {
+	storagePtr, err := secretsStorage.GetRegistry().GetStorage(storageName)
+	if err != nil {
+		return nil, err
+	}
+	lister, isLister := (*storagePtr).(secretsStorage.EntryLister)
+	if !isLister {
+		return nil, errors.Newf("the storage %s cannot list its entries", storageName)
+	}
+	return lister.ListEntries(prefix)
+}
+
+// getShellDialect returns the shell dialect selected by the --shell flag. If the flag is not set, the shell is taken
+// from $ENVMANAGER_SHELL (set by the wrappers) and finally detected from $SHELL.
+func getShellDialect() (environment.ShellDialect, error) {
+	if flagShell != "" {
+		return environment.GetDialect(flagShell)
+	}
+	if shell := os.Getenv(envManagerShellName); shell != "" {
+		return environment.GetDialect(shell)
+	}
+	return environment.DetectDialect(os.Getenv("SHELL")), nil
+}
+
+// getLoadedProfiles returns the names of the loaded profiles, including changes to
+// the environment which are not yet written as statements.
+func getLoadedProfiles(env *environment.Environment) []string {
+	value, _ := env.Lookup(envManagerLoadedProfilesName)
+	return helper.SliceStringRemove("", strings.Split(value, ","))
+}
+
+// ignoredConfigFiles holds the config files skipped by InitConfigForStatements, writeStatements warns about them
+var ignoredConfigFiles []string
+
 // InitConfig is a wrapper around the simple initConfig() method. With this adapter you can write
-// PreRun: InitConfig, in your command object.
+// PreRun: InitConfig, in your command object. It warns on stderr about config files which were skipped because they
+// are not allowed, unless this shell session was already warned about them.
 func InitConfig(_ *cobra.Command, _ []string) {
-	initConfig()
+	warned := getWarnedConfigFiles(os.Getenv(envManagerWarnedConfigsName))
+	for _, file := range initConfig() {
+		if !slices.Contains(warned, file) {
+			_, _ = fmt.Fprintln(os.Stderr, ignoredConfigWarning(file))
+		}
+	}
+}
+
+// InitConfigForStatements is InitConfig for the commands whose stderr is evaluated by the wrapper (load, unload and
+// _hook). The warnings are written as statements by writeStatements instead, which records them in the environment,
+// so every file is only warned about once per shell session.
+func InitConfigForStatements(_ *cobra.Command, _ []string) {
+	ignoredConfigFiles = initConfig()
+}
+
+// writeStatements writes the statements updating the environment to stderr, where the wrapper evaluates them. They
+// start with statements printing the warnings about ignored config files which were not warned about yet.
+func writeStatements(env *environment.Environment, dialect environment.ShellDialect) error {
+	warnedValue, _ := env.Lookup(envManagerWarnedConfigsName)
+	warned := getWarnedConfigFiles(warnedValue)
+	var statements []string
+	for _, file := range ignoredConfigFiles {
+		if slices.Contains(warned, file) {
+			continue
+		}
+		warned = append(warned, file)
+		statements = append(statements, dialect.WarningStatement(ignoredConfigWarning(file)))
+	}
+	if len(statements) > 0 {
+		err := env.Set(envManagerWarnedConfigsName, strings.Join(warned, string(os.PathListSeparator)))
+		if err != nil {
+			return err
+		}
+	}
+	if changes := env.WriteStatementsFor(dialect); changes != "" {
+		statements = append(statements, changes)
+	}
+	print(strings.Join(statements, ";"))
+	return nil
+}
+
+// getWarnedConfigFiles splits the value of envManagerWarnedConfigsName into the config files
+func getWarnedConfigFiles(value string) []string {
+	return helper.SliceStringRemove("", filepath.SplitList(value))
+}
+
+// ignoredConfigWarning returns the warning about a config file which was skipped because it is not allowed
+func ignoredConfigWarning(file string) string {
+	return fmt.Sprintf("envManager: ignoring %s, it is not allowed or was modified. Review it and run envManager allow %s", file, filepath.Dir(file))
+}
+
+// loadTrustStore loads the trust store from the user's config directory
+func loadTrustStore() (*secretsStorage.TrustStore, error) {
+	trustStorePath, err := secretsStorage.GetDefaultTrustStorePath()
+	if err != nil {
+		return nil, err
+	}
+	return secretsStorage.LoadTrustStore(trustStorePath)
+}
+
+// getConfigFileArgument returns the config file named by the arguments. A directory is replaced by the config file in
+// it, without arguments the config file of the working directory is returned.
+func getConfigFileArgument(args []string) (string, error) {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if fileInfo, err := os.Stat(path); err == nil && fileInfo.IsDir() {
+		path = filepath.Join(path, ".envManager.yml")
+	}
+	return path, nil
 }
 
 // promptYesNo shows a prompt for a yes / no question. The (Y|N) is added to the prompt automatically.
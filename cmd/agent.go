@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"envManager/environment"
+	"envManager/secretsStorage"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+var flagAgentIdleTimeout time.Duration
+var flagAgentForeground bool
+var flagAgentSocket string
+
+// agentCmd represents the agent command
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Start an agent which keeps your storages unlocked",
+	Long: `Start an agent in the background which keeps your storages (e.g. keepass databases)
+unlocked, so you only have to enter the password once. After being idle for the idle
+timeout, the agent locks all storages again. The agent is announced to envManager
+in $` + secretsStorage.AgentSocketVariableName + `, which this command sets in your shell.
+
+Use "envManager agent lock" to lock all storages immediately and "envManager agent stop"
+to stop the agent.`,
+	Args: cobra.NoArgs,
+	Run:  runAgent,
+}
+
+// agentLockCmd represents the agent lock command
+var agentLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Lock all storages held by the agent",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := getAgentClient()
+		cobra.CheckErr(err)
+		cobra.CheckErr(client.Lock())
+		fmt.Println("Locked all storages of the agent")
+	},
+}
+
+// agentStopCmd represents the agent stop command
+var agentStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the agent",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dialect, err := getShellDialect()
+		cobra.CheckErr(err)
+		client, err := getAgentClient()
+		cobra.CheckErr(err)
+		cobra.CheckErr(client.Stop())
+
+		env := environment.NewEnvironment()
+		env.Load()
+		cobra.CheckErr(env.Unset(secretsStorage.AgentSocketVariableName))
+		cobra.CheckErr(env.Unset(secretsStorage.AgentPidVariableName))
+		print(env.WriteStatementsFor(dialect))
+	},
+}
+
+func runAgent(_ *cobra.Command, _ []string) {
+	if flagAgentForeground {
+		cobra.CheckErr(serveAgent(flagAgentSocket, flagAgentIdleTimeout))
+		return
+	}
+
+	dialect, err := getShellDialect()
+	cobra.CheckErr(err)
+	if client, err := getAgentClient(); err == nil && client.Ping() == nil {
+		fmt.Println("The agent is already running")
+		return
+	}
+
+	// only the user may access the directory and therefore the socket
+	socketDir, err := os.MkdirTemp("", "envManager-agent-")
+	cobra.CheckErr(err)
+	socketPath := filepath.Join(socketDir, "agent.sock")
+
+	executable, err := os.Executable()
+	cobra.CheckErr(err)
+	agentProcess := exec.Command(
+		executable,
+		"agent",
+		"--foreground",
+		"--socket", socketPath,
+		"--idle-timeout", flagAgentIdleTimeout.String(),
+	)
+	// detach the agent from the terminal, so it survives closing the shell
+	agentProcess.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	cobra.CheckErr(agentProcess.Start())
+	pid := agentProcess.Process.Pid
+	_ = agentProcess.Process.Release()
+
+	if err := waitForAgent(secretsStorage.NewAgentClient(socketPath)); err != nil {
+		_ = os.RemoveAll(socketDir)
+		cobra.CheckErr(err)
+	}
+
+	env := environment.NewEnvironment()
+	env.Load()
+	cobra.CheckErr(env.Set(secretsStorage.AgentSocketVariableName, socketPath))
+	cobra.CheckErr(env.Set(secretsStorage.AgentPidVariableName, strconv.Itoa(pid)))
+	print(env.WriteStatementsFor(dialect))
+	fmt.Printf("Agent started with pid %d\n", pid)
+}
+
+// serveAgent runs the agent on socketPath until it is stopped. The directory of the socket is removed afterwards.
+func serveAgent(socketPath string, idleTimeout time.Duration) error {
+	if socketPath == "" {
+		return errors.New("--socket is required with --foreground")
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer os.RemoveAll(filepath.Dir(socketPath))
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		_ = listener.Close()
+		return err
+	}
+
+	agent := secretsStorage.NewAgent(idleTimeout)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		<-signals
+		agent.Stop()
+	}()
+	return agent.Serve(listener)
+}
+
+// waitForAgent waits until the freshly started agent accepts connections
+func waitForAgent(client *secretsStorage.AgentClient) error {
+	var err error
+	for i := 0; i < 50; i++ {
+		if err = client.Ping(); err == nil {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("the agent did not start: %w", err)
+}
+
+// getAgentClient returns a client for the agent announced in the environment
+func getAgentClient() (*secretsStorage.AgentClient, error) {
+	socketPath := os.Getenv(secretsStorage.AgentSocketVariableName)
+	if socketPath == "" {
+		return nil, fmt.Errorf("no agent is running, $%s is not set", secretsStorage.AgentSocketVariableName)
+	}
+	return secretsStorage.NewAgentClient(socketPath), nil
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+	agentCmd.AddCommand(agentLockCmd)
+	agentCmd.AddCommand(agentStopCmd)
+	agentCmd.Flags().DurationVar(&flagAgentIdleTimeout, "idle-timeout", 15*time.Minute, "Lock all storages after being idle for this duration, 0 disables locking")
+	agentCmd.Flags().BoolVar(&flagAgentForeground, "foreground", false, "Run the agent in the foreground")
+	agentCmd.Flags().StringVar(&flagAgentSocket, "socket", "", "Socket to listen on, required with --foreground")
+	_ = agentCmd.Flags().MarkHidden("foreground")
+	_ = agentCmd.Flags().MarkHidden("socket")
+}
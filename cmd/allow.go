@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+// allowCmd represents the allow command
+var allowCmd = &cobra.Command{
+	Use:   "allow [path]",
+	Short: "Allow a config file to be loaded",
+	Long: `Config files found in the working directory or its parents are only loaded after
+you allowed them. Review the file before allowing it, a config file can map the directory
+to any of your profiles. If the file is changed, it must be allowed again.
+
+The path can be a config file or a directory containing one. Without a path, the config
+file in the working directory is allowed.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		configFile, err := getConfigFileArgument(args)
+		cobra.CheckErr(err)
+		trustStore, err := loadTrustStore()
+		cobra.CheckErr(err)
+		cobra.CheckErr(trustStore.Allow(configFile))
+		cobra.CheckErr(trustStore.Save())
+		fmt.Printf("Allowed %s\n", configFile)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(allowCmd)
+}
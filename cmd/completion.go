@@ -30,8 +30,22 @@ Zsh:
   # somewhere in your $fpath
   envManager completion zsh > /file/in/fpath
 
-  # You will need to start a new shell for this setup to take effect.`,
-	ValidArgs: []string{"bash", "zsh"},
+  # You will need to start a new shell for this setup to take effect.
+
+Fish:
+
+  $ envManager completion fish | source
+
+  # To load completions for each session, execute once:
+  $ envManager completion fish > ~/.config/fish/completions/envManager.fish
+
+PowerShell:
+
+  PS> envManager completion powershell | Out-String | Invoke-Expression
+
+  # To load completions for every new session, add the output of the above command
+  # to your PowerShell profile.`,
+	ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
 	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
 	Run: func(cmd *cobra.Command, args []string) {
 		var err error
@@ -40,6 +54,10 @@ Zsh:
 			err = cmd.Root().GenBashCompletion(os.Stdout)
 		case "zsh":
 			err = cmd.Root().GenZshCompletion(os.Stdout)
+		case "fish":
+			err = cmd.Root().GenFishCompletion(os.Stdout, true)
+		case "powershell":
+			err = cmd.Root().GenPowerShellCompletionWithDesc(os.Stdout)
 		}
 		cobra.CheckErr(err)
 	},
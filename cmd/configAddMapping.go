@@ -14,13 +14,15 @@ import (
 
 var flagAddMappingSelect bool
 var flagAddMappingLocal bool
+var flagAddMappingExact bool
 
 // configAddMappingCmd represents the mapping command
 var configAddMappingCmd = &cobra.Command{
 	Use:   "mapping",
 	Short: "Add a directory mapping to your config",
-	Long:  `A directory mapping links one or more profiles to the current directory.`,
-	Args:  cobra.NoArgs,
+	Long: `A directory mapping links one or more profiles to the current directory and its
+subdirectories. Use --exact to map the current directory only.`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		var configPath string
 		config := secretsStorage.NewConfiguration()
@@ -30,10 +32,19 @@ var configAddMappingCmd = &cobra.Command{
 		workingDir, err := os.Getwd()
 		cobra.CheckErr(err)
 
+		// the local config file stays allowed if it is created here or was allowed before
+		keepAllowed := false
 		if flagAddMappingLocal {
 			localPath := filepath.Join(workingDir, ".envManager.yml")
 
+			trustStore, err := loadTrustStore()
+			cobra.CheckErr(err)
+			if status, err := trustStore.GetStatus(localPath); err == nil && status == secretsStorage.TrustStatusTrusted {
+				keepAllowed = true
+			}
+
 			if _, statErr := os.Stat(localPath); os.IsNotExist(statErr) {
+				keepAllowed = true
 				// file does not exist, create it
 				file, err := os.Create(localPath)
 				cobra.CheckErr(err)
@@ -72,11 +83,20 @@ var configAddMappingCmd = &cobra.Command{
 			}
 		}
 
-		config.DirectoryMapping[workingDir] = profilesToMap
+		config.DirectoryMapping[workingDir] = secretsStorage.DirectoryMapping{
+			Profiles: profilesToMap,
+			Exact:    flagAddMappingExact,
+		}
 
 		cobra.CheckErr(
 			config.WriteToFile(configPath, true),
 		)
+		if keepAllowed {
+			trustStore, err := loadTrustStore()
+			cobra.CheckErr(err)
+			cobra.CheckErr(trustStore.Allow(configPath))
+			cobra.CheckErr(trustStore.Save())
+		}
 		fmt.Printf("Mapped the profiles (%s) to your current working directory.", strings.Join(profilesToMap, ", "))
 	},
 }
@@ -85,4 +105,5 @@ func init() {
 	configAddCmd.AddCommand(configAddMappingCmd)
 	configAddMappingCmd.Flags().BoolVarP(&flagAddMappingSelect, "select", "s", false, "Select profiles interactively")
 	configAddMappingCmd.Flags().BoolVarP(&flagAddMappingLocal, "local", "l", false, "Add to local config in this working directory instead of the global config file")
+	configAddMappingCmd.Flags().BoolVarP(&flagAddMappingExact, "exact", "e", false, "Map the current directory only, not its subdirectories")
 }
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"envManager/environment"
 	"envManager/helper"
 	"envManager/secretsStorage"
 	"fmt"
@@ -14,6 +15,7 @@ import (
 var flagAddProfileDependencies bool
 var flagAddProfileConstEnv bool
 var flagAddProfileEnv bool
+var flagAddProfilePath string
 
 // configAddProfileCmd represents the profile command
 var configAddProfileCmd = &cobra.Command{
@@ -75,7 +77,9 @@ var configAddProfileCmd = &cobra.Command{
 			Storage:   storageAdapter,
 		}
 
-		if needsPath {
+		if needsPath && flagAddProfilePath != "" {
+			profile.Path = flagAddProfilePath
+		} else if needsPath {
 			var err error
 			profile.Path, err = helper.GetInput().PromptString("Enter the path to the entry")
 			cobra.CheckErr(err)
@@ -118,17 +122,8 @@ func promptForConstEnv() (map[string]string, error) {
 	fmt.Println("Define constant environment variables")
 
 	keyPrompt := promptui.Prompt{
-		Label: "Variable name",
-		Validate: func(input string) error {
-			match, err := regexp.MatchString("^[a-zA-Z0-9_]+$", input)
-			if err != nil {
-				return err
-			}
-			if !match {
-				return errors.New("Only letters, numbers and underscore is allowed")
-			}
-			return nil
-		},
+		Label:    "Variable name",
+		Validate: environment.ValidateVariableName,
 	}
 	valuePrompt := promptui.Prompt{
 		Label: "Variable value",
@@ -155,17 +150,8 @@ func promptAndAddEnvMapping(profile *secretsStorage.Profile) error {
 	fmt.Println("Define environment mapping")
 
 	keyPrompt := promptui.Prompt{
-		Label: "Variable name",
-		Validate: func(input string) error {
-			match, err := regexp.MatchString("^[a-zA-Z0-9_]+$", input)
-			if err != nil {
-				return err
-			}
-			if !match {
-				return errors.New("Only letters, numbers and underscore is allowed")
-			}
-			return nil
-		},
+		Label:    "Variable name",
+		Validate: environment.ValidateVariableName,
 	}
 
 	for {
@@ -223,4 +209,11 @@ func init() {
 	configAddProfileCmd.Flags().BoolVarP(&flagAddProfileDependencies, "dependencies", "d", false, "Select dependencies interactively")
 	configAddProfileCmd.Flags().BoolVarP(&flagAddProfileConstEnv, "constEnv", "o", false, "Specify constant environment variables interactively")
 	configAddProfileCmd.Flags().BoolVarP(&flagAddProfileEnv, "env", "e", false, "Specify environment variable mapping interactively")
+	configAddProfileCmd.Flags().StringVarP(&flagAddProfilePath, "path", "p", "", "Path to the entry, asked for interactively if not set")
+	_ = configAddProfileCmd.RegisterFlagCompletionFunc("path", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return []string{}, cobra.ShellCompDirectiveNoFileComp
+		}
+		return CompleteEntryPaths(args[0], toComplete)
+	})
 }
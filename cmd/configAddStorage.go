@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/spf13/cobra"
 	"gopkg.in/errgo.v2/fmt/errors"
+	"maps"
 	"regexp"
 	"slices"
 	"strings"
@@ -12,11 +13,16 @@ import (
 
 var storageType string
 var storageName string
+var flagAddStorageOptions []string
 
 // configAddStorageCmd represents the storage command
 var configAddStorageCmd = &cobra.Command{
 	Use:   "storage [type] [name]",
 	Short: "Add a storage adapter to your configuration",
+	Long: `Add a storage adapter with its default config to your configuration. Set the config
+options with --option, e.g. for a keepass database secured with a key file:
+
+  envManager config add storage keepass work -o path=~/work.kdbx -o keyFile=~/work.key`,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 2 {
 			return errors.New("type and name are required")
@@ -64,6 +70,7 @@ var configAddStorageCmd = &cobra.Command{
 		}
 		defaultConfig, err := secretsStorage.GetStorageAdapterDefaultConfig(storageType)
 		cobra.CheckErr(err)
+		cobra.CheckErr(applyStorageOptions(defaultConfig, flagAddStorageOptions))
 		config.Storages[storageName] = secretsStorage.Storage{
 			StorageType: storageType,
 			Config:      defaultConfig,
@@ -76,6 +83,24 @@ var configAddStorageCmd = &cobra.Command{
 	},
 }
 
+// applyStorageOptions sets the options (given as key=value) in the storage config. Only options known by the default
+// config of the storage adapter can be set.
+func applyStorageOptions(config map[string]string, options []string) error {
+	for _, option := range options {
+		key, value, found := strings.Cut(option, "=")
+		if !found {
+			return errors.Newf("option %s must be given as key=value", option)
+		}
+		if _, known := config[key]; !known {
+			knownKeys := slices.Sorted(maps.Keys(config))
+			return errors.Newf("unknown option %s. Known options: %s", key, strings.Join(knownKeys, ", "))
+		}
+		config[key] = value
+	}
+	return nil
+}
+
 func init() {
 	configAddCmd.AddCommand(configAddStorageCmd)
+	configAddStorageCmd.Flags().StringArrayVarP(&flagAddStorageOptions, "option", "o", nil, "Set a config option of the storage as key=value, can be repeated")
 }
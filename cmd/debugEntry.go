@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/spf13/cobra"
 	"gopkg.in/errgo.v2/fmt/errors"
+	"strings"
 )
 
 // debugEntryCmd represents the entry command
@@ -30,14 +31,35 @@ var debugEntryCmd = &cobra.Command{
 		entry, err := (*storagePtr).GetEntry(path)
 		cobra.CheckErr(err)
 		fmt.Printf("Attributes of %s in %s:\n", path, storageName)
-		attributeNames := entry.GetAttributeNames()
-		for i := 0; i < len(attributeNames); i++ {
-			fmt.Printf("- %s\n", attributeNames[i])
+		var attachmentNames []string
+		for _, name := range entry.GetAttributeNames() {
+			if strings.HasPrefix(name, secretsStorage.KeepassAttachmentPrefix) {
+				attachmentNames = append(attachmentNames, name)
+				continue
+			}
+			fmt.Printf("- %s\n", name)
+		}
+		if len(attachmentNames) > 0 {
+			fmt.Println("Attachments (use them like attributes):")
+			for _, name := range attachmentNames {
+				content, _ := entry.GetAttribute(name)
+				fmt.Printf("- %s (%d bytes)\n", name, len(*content))
+			}
 		}
 		if (*storagePtr).IsCaseSensitive() {
 			fmt.Println("This storage provider is case-sensitive!")
 		}
 	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		switch len(args) {
+		case 0:
+			return CompleteStorages(cmd, args, toComplete)
+		case 1:
+			return CompleteEntryPaths(args[0], toComplete)
+		default:
+			return []string{}, cobra.ShellCompDirectiveNoFileComp
+		}
+	},
 }
 
 func init() {
@@ -9,7 +9,7 @@ import (
 // debugFilesCmd represents the debug files command
 var debugFilesCmd = &cobra.Command{
 	Use:   "files",
-	Short: "Shows which config files will be loaded in which order",
+	Short: "Shows which config files will be loaded in which order and if they are allowed",
 	// this empty PersistentPreRun function is only set to overwrite the inherited PersistedPreRun since loading the
 	// config and merging files is not needed and will actually make this command less useful if there are collisions
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
@@ -17,11 +17,27 @@ var debugFilesCmd = &cobra.Command{
 		dir, err := os.Getwd()
 		cobra.CheckErr(err)
 
+		trustStore, err := loadTrustStore()
+		cobra.CheckErr(err)
+
+		// only allowed files are processed, so the status is shown for each file
+		var items []string
+		for _, configFile := range discoverConfigFiles(dir, flagConfigFile) {
+			if configFile == flagConfigFile {
+				items = append(items, configFile+" (main config file)")
+				continue
+			}
+			status, err := trustStore.GetStatus(configFile)
+			cobra.CheckErr(err)
+			items = append(items, fmt.Sprintf("%s (%s)", configFile, status))
+		}
+
 		_, _ = fmt.Fprintln(os.Stderr, "These files will be processed in this order (later files override earlier files):")
+		_, _ = fmt.Fprintln(os.Stderr, "Only the main config file and allowed files are loaded, see envManager allow --help")
 		_, _ = fmt.Fprint(
 			os.Stderr,
 			formatList(
-				discoverConfigFiles(dir, flagConfigFile),
+				items,
 				"\t- ",
 				"\n",
 				"",
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"envManager/secretsStorage"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// debugMappingCmd represents the debug mapping command
+var debugMappingCmd = &cobra.Command{
+	Use:   "mapping [directory]",
+	Short: "Explains which directory mapping applies to a directory",
+	Long: `The debug mapping command shows which directory mapping applies to the given
+directory (or the current working directory) and why the other mappings do not.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var dir string
+		var err error
+		if len(args) == 0 {
+			dir, err = os.Getwd()
+		} else {
+			dir, err = filepath.Abs(args[0])
+		}
+		cobra.CheckErr(err)
+
+		matches := secretsStorage.GetRegistry().ExplainDirectoryMapping(dir)
+		fmt.Printf("Directory: %s\n", dir)
+		if len(matches) == 0 || !matches[0].Matched {
+			fmt.Println("No directory mapping applies to this directory")
+		} else {
+			fmt.Printf(
+				"Applied mapping: %s\nReason: %s\nProfiles: %s\n",
+				matches[0].Path,
+				matches[0].Reason,
+				strings.Join(matches[0].Profiles, ", "),
+			)
+			matches = matches[1:]
+		}
+		if len(matches) > 0 {
+			fmt.Println("Other mappings:")
+		}
+		for _, match := range matches {
+			state := "not applied"
+			if match.Matched {
+				state = "overridden by the applied mapping"
+			}
+			fmt.Printf(" - %s (%s): %s\n", match.Path, state, match.Reason)
+		}
+	},
+}
+
+func init() {
+	debugCmd.AddCommand(debugMappingCmd)
+}
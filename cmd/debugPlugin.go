@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"envManager/storagePlugin"
+	"fmt"
+	"github.com/spf13/cobra"
+	"maps"
+	"slices"
+	"strings"
+)
+
+var flagPluginOptions []string
+var flagPluginKey string
+var flagPluginMissingKey string
+
+// debugPluginCmd represents the debug plugin command
+var debugPluginCmd = &cobra.Command{
+	Use:   "plugin [type or executable]",
+	Short: "Lists the storage adapter plugins or runs the conformance tests of a plugin",
+	Long: `Without arguments, the storage adapter plugins found on your PATH are listed. Given a
+storage type (or the path of a plugin executable), the conformance tests of the plugin
+protocol are run against the plugin, e.g.
+
+  envManager debug plugin ./envManager-storage-json -o path=entries.json --key entry1`,
+	Args: cobra.MaximumNArgs(1),
+	// plugins can be tested without a configuration
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			plugins := storagePlugin.FindPlugins()
+			fmt.Println("Storage adapter plugins:")
+			for _, storageType := range slices.Sorted(maps.Keys(plugins)) {
+				fmt.Printf("- %s (%s)\n", storageType, plugins[storageType])
+			}
+			return
+		}
+
+		executable := args[0]
+		if !strings.ContainsRune(executable, '/') {
+			var err error
+			executable, err = storagePlugin.FindPlugin(args[0])
+			cobra.CheckErr(err)
+		}
+		client := &storagePlugin.Client{Executable: executable}
+		response, err := client.Call(storagePlugin.Request{Method: storagePlugin.MethodGetDefaultConfig})
+		cobra.CheckErr(err)
+		config := response.Config
+		if config == nil {
+			config = map[string]string{}
+		}
+		cobra.CheckErr(applyStorageOptions(config, flagPluginOptions))
+
+		fmt.Printf("Testing %s\n", executable)
+		results := storagePlugin.TestPlugin(executable, storagePlugin.ConformanceOptions{
+			Config:     config,
+			Key:        flagPluginKey,
+			MissingKey: flagPluginMissingKey,
+		})
+		failed := 0
+		for _, result := range results {
+			if result.Err != nil {
+				failed++
+				fmt.Printf("FAIL %s: %s\n", result.Name, result.Err)
+				continue
+			}
+			fmt.Printf("ok   %s\n", result.Name)
+		}
+		if failed > 0 {
+			cobra.CheckErr(fmt.Errorf("%d of %d conformance tests failed", failed, len(results)))
+		}
+	},
+}
+
+func init() {
+	debugCmd.AddCommand(debugPluginCmd)
+	debugPluginCmd.Flags().StringArrayVarP(&flagPluginOptions, "option", "o", nil, "Set a config option of the storage as key=value, can be repeated")
+	debugPluginCmd.Flags().StringVarP(&flagPluginKey, "key", "k", "", "Path of an existing entry")
+	debugPluginCmd.Flags().StringVar(&flagPluginMissingKey, "missing-key", "", "Path of an entry which does not exist")
+}
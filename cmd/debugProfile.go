@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"cmp"
 	"envManager/secretsStorage"
 	"fmt"
 	"github.com/spf13/cobra"
+	"strings"
 )
 
 // debugProfileCmd represents the profile command
@@ -37,10 +39,40 @@ var debugProfileCmd = &cobra.Command{
 			debugProfilePrintEnv(profile.ConstEnv)
 		}
 
-		fmt.Printf("Provides dynamic environment variables: %t\n", len(profile.Env) > 0)
+		fmt.Printf("Provides dynamic environment variables: %t\n", len(profile.Env)+len(profile.EnvRefs) > 0)
 		if len(profile.Env) > 0 {
 			debugProfilePrintEnv(profile.Env)
 		}
+		for key, reference := range profile.EnvRefs {
+			fmt.Printf(
+				" %s : %s of %s in %s\n",
+				key,
+				reference.Attribute,
+				cmp.Or(reference.Path, profile.Path),
+				cmp.Or(reference.Storage, profile.Storage),
+			)
+		}
+
+		fmt.Printf("Provides files: %t\n", len(profile.Files) > 0)
+		for key, reference := range profile.Files {
+			fmt.Printf(
+				" %s : %s of %s in %s\n",
+				key,
+				reference.Attribute,
+				cmp.Or(reference.Path, profile.Path),
+				cmp.Or(reference.Storage, profile.Storage),
+			)
+		}
+
+		fmt.Printf("Extends lists: %t\n", len(profile.Lists) > 0)
+		for key, list := range profile.Lists {
+			fmt.Printf(
+				" %s : prepends %s, appends %s\n",
+				key,
+				strings.Join(list.Prepend, ", "),
+				strings.Join(list.Append, ", "),
+			)
+		}
 	},
 }
 
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+// denyCmd represents the deny command
+var denyCmd = &cobra.Command{
+	Use:   "deny [path]",
+	Short: "Deny a config file to be loaded",
+	Long: `Revoke the permission to load a config file. Denied files are ignored without a
+warning until they are allowed again.
+
+The path can be a config file or a directory containing one. Without a path, the config
+file in the working directory is denied.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		configFile, err := getConfigFileArgument(args)
+		cobra.CheckErr(err)
+		trustStore, err := loadTrustStore()
+		cobra.CheckErr(err)
+		cobra.CheckErr(trustStore.Deny(configFile))
+		cobra.CheckErr(trustStore.Save())
+		fmt.Printf("Denied %s\n", configFile)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(denyCmd)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"envManager/environment"
+	"envManager/helper"
+	"envManager/secretsStorage"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+var flagExecProfiles []string
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:   "exec [-p profile]... -- command [args...]",
+	Short: "Run a command with profiles loaded",
+	Long: `Run a command with one or more profiles loaded into its environment, without
+changing the environment of this shell. If called without profiles, the directory
+mapping for the current working directory will be loaded.
+
+envManager replaces itself with the command, so the exit code of envManager is
+the exit code of the command.`,
+	Example: "  envManager exec -p aws -p db -- terraform plan",
+	Args:    cobra.MinimumNArgs(1),
+	PreRun:  InitConfig,
+	Run:     runExec,
+}
+
+func runExec(_ *cobra.Command, args []string) {
+	env := environment.NewEnvironment()
+	env.Load()
+	// the command replaces envManager and keeps its pid, its files are removed once it exited
+	env.SetSessionOwner(os.Getpid())
+
+	profilesToLoad, err := resolveProfiles(flagExecProfiles)
+	cobra.CheckErr(err)
+	cobra.CheckErr(loadProfiles(&env, profilesToLoad))
+
+	// the profiles might have changed the PATH, the command must be searched in the new one
+	if path, exists := env.Lookup("PATH"); exists {
+		cobra.CheckErr(os.Setenv("PATH", path))
+	}
+	binary, err := exec.LookPath(args[0])
+	cobra.CheckErr(err)
+	// syscall.Exec only returns if it failed to replace this process
+	cobra.CheckErr(syscall.Exec(binary, args, env.Environ()))
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+	// everything after the command belongs to the command, even if it looks like a flag of envManager
+	execCmd.Flags().SetInterspersed(false)
+	execCmd.Flags().StringArrayVarP(&flagExecProfiles, "profile", "p", []string{}, "Profile to load, can be given multiple times")
+	_ = execCmd.RegisterFlagCompletionFunc("profile", func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		initConfig()
+		return helper.Completion(
+			secretsStorage.GetRegistry().GetProfileNames(),
+			flagExecProfiles,
+			toComplete,
+		), cobra.ShellCompDirectiveNoFileComp
+	})
+}
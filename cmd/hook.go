@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"envManager/environment"
+	"envManager/helper"
+	"envManager/secretsStorage"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"slices"
+	"strings"
+)
+
+// The name of the environment variable containing the profiles loaded by the directory hook
+const envManagerAutoLoadedProfilesName = "ENVMANAGER_AUTOLOADED"
+
+// hookScripts contains the script installing the directory hook for each supported shell. The scripts call the
+// envManager function defined by the wrapper, which evaluates the output of _hook.
+var hookScripts = map[string]string{
+	"bash": `_envManager_hook() {
+  local previous_exit_status=$?
+  if [[ "${_envManager_hook_pwd-}" != "$PWD" ]]; then
+    _envManager_hook_pwd=$PWD
+    envManager _hook --shell bash
+  fi
+  return $previous_exit_status
+}
+if [[ ";${PROMPT_COMMAND[*]:-};" != *";_envManager_hook;"* ]]; then
+  PROMPT_COMMAND="_envManager_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+`,
+	"zsh": `_envManager_hook() {
+  envManager _hook --shell zsh
+}
+typeset -ag chpwd_functions
+if (( ! ${chpwd_functions[(I)_envManager_hook]} )); then
+  chpwd_functions=(_envManager_hook $chpwd_functions)
+fi
+_envManager_hook
+`,
+	"fish": `function _envManager_hook --on-variable PWD
+    envManager _hook --shell fish
+end
+_envManager_hook
+`,
+}
+
+// hookCmd represents the hook command
+var hookCmd = &cobra.Command{
+	Use:   "hook [shell]",
+	Short: "Print a hook which loads directory mappings automatically",
+	Long: `Print a shell hook which loads the directory mapping whenever you change the working
+directory and unloads it again once you leave the mapped directory. Profiles you load
+yourself are not touched. The hook needs the envManager function of the wrapper.
+
+Bash (in ~/.bashrc, after sourcing wrapper.sh):
+
+  eval "$(envManager-bin hook bash)"
+
+Zsh (in ~/.zshrc, after sourcing wrapper.sh):
+
+  eval "$(envManager-bin hook zsh)"
+
+Fish (in ~/.config/fish/config.fish, after sourcing wrapper.fish):
+
+  envManager-bin hook fish | source`,
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(hookScripts[args[0]])
+	},
+}
+
+// hookRunCmd represents the _hook command called by the hook scripts
+var hookRunCmd = &cobra.Command{
+	Use:    "_hook",
+	Short:  "Load and unload the directory mapping of the working directory",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	PreRun: InitConfigForStatements,
+	Run:    runHook,
+}
+
+func runHook(_ *cobra.Command, _ []string) {
+	dialect, err := getShellDialect()
+	cobra.CheckErr(err)
+	env := environment.NewEnvironment()
+	env.Load()
+
+	workingDir, err := os.Getwd()
+	cobra.CheckErr(err)
+	var wantedProfiles []string
+	if secretsStorage.GetRegistry().HasDirectoryMapping(workingDir) {
+		wantedProfiles, err = resolveProfiles(nil)
+		cobra.CheckErr(err)
+	}
+
+	autoLoadedValue, _ := env.Lookup(envManagerAutoLoadedProfilesName)
+	changes := getHookProfileChanges(
+		wantedProfiles,
+		getLoadedProfiles(&env),
+		helper.SliceStringRemove("", strings.Split(autoLoadedValue, ",")),
+	)
+	if len(changes.toLoad) == 0 && len(changes.toUnload) == 0 {
+		cobra.CheckErr(writeStatements(&env, dialect))
+		return
+	}
+
+	cobra.CheckErr(unloadHookProfiles(&env, changes.toUnload))
+	cobra.CheckErr(loadProfiles(&env, changes.toLoad))
+
+	if len(changes.autoLoaded) > 0 {
+		cobra.CheckErr(env.Set(envManagerAutoLoadedProfilesName, strings.Join(changes.autoLoaded, ",")))
+	} else {
+		cobra.CheckErr(env.Unset(envManagerAutoLoadedProfilesName))
+	}
+	cobra.CheckErr(writeStatements(&env, dialect))
+}
+
+// hookProfileChanges are the profiles the hook loads and unloads when the working directory changes
+type hookProfileChanges struct {
+	toUnload []string
+	toLoad   []string
+	//autoLoaded are the profiles loaded by the hook afterwards
+	autoLoaded []string
+}
+
+// getHookProfileChanges computes the changes of the hook from the profiles wanted in the working directory, the
+// loaded profiles and the profiles loaded by the hook before. Only what the hook loaded is unloaded, profiles loaded by
+// hand stay and are not loaded again.
+func getHookProfileChanges(wantedProfiles, loadedProfiles, autoLoadedProfiles []string) hookProfileChanges {
+	var changes hookProfileChanges
+	for _, name := range autoLoadedProfiles {
+		if slices.Contains(wantedProfiles, name) {
+			changes.autoLoaded = append(changes.autoLoaded, name)
+		} else {
+			changes.toUnload = append(changes.toUnload, name)
+		}
+	}
+	for _, name := range wantedProfiles {
+		if !slices.Contains(loadedProfiles, name) {
+			changes.toLoad = append(changes.toLoad, name)
+		}
+	}
+	changes.autoLoaded = append(changes.autoLoaded, changes.toLoad...)
+	return changes
+}
+
+// unloadHookProfiles unloads the profiles which left the scope. Profiles defined in
+// a config file of the directory just left are not known anymore, the variables
+// they set are found in the recorded previous values instead.
+func unloadHookProfiles(env *environment.Environment, profilesToUnload []string) error {
+	registry := secretsStorage.GetRegistry()
+	var knownProfiles, unknownProfiles []string
+	for _, name := range profilesToUnload {
+		if registry.HasProfile(name) {
+			knownProfiles = append(knownProfiles, name)
+			continue
+		}
+		if err := env.PopOwner(name); err != nil {
+			return err
+		}
+		unknownProfiles = append(unknownProfiles, name)
+	}
+	if err := unloadProfiles(env, knownProfiles); err != nil {
+		return err
+	}
+	loadedProfiles := getLoadedProfiles(env)
+	for _, name := range unknownProfiles {
+		loadedProfiles = helper.SliceStringRemove(name, loadedProfiles)
+	}
+	return env.Set(envManagerLoadedProfilesName, strings.Join(loadedProfiles, ","))
+}
+
+func init() {
+	rootCmd.AddCommand(hookCmd)
+	rootCmd.AddCommand(hookRunCmd)
+}
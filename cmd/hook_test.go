@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_getHookProfileChanges(t *testing.T) {
+	tests := []struct {
+		name               string
+		wantedProfiles     []string
+		loadedProfiles     []string
+		autoLoadedProfiles []string
+		want               hookProfileChanges
+	}{
+		{
+			name:           "Entering a mapped directory",
+			wantedProfiles: []string{"dev", "aws"},
+			want:           hookProfileChanges{toLoad: []string{"dev", "aws"}, autoLoaded: []string{"dev", "aws"}},
+		},
+		{
+			name:               "Leaving a mapped directory",
+			loadedProfiles:     []string{"dev", "aws"},
+			autoLoadedProfiles: []string{"dev", "aws"},
+			want:               hookProfileChanges{toUnload: []string{"dev", "aws"}},
+		},
+		{
+			name:               "Moving between two mapped directories",
+			wantedProfiles:     []string{"aws", "k8s"},
+			loadedProfiles:     []string{"dev", "aws"},
+			autoLoadedProfiles: []string{"dev", "aws"},
+			want: hookProfileChanges{
+				toUnload:   []string{"dev"},
+				toLoad:     []string{"k8s"},
+				autoLoaded: []string{"aws", "k8s"},
+			},
+		},
+		{
+			name:               "Staying in a mapped directory",
+			wantedProfiles:     []string{"dev"},
+			loadedProfiles:     []string{"dev"},
+			autoLoadedProfiles: []string{"dev"},
+			want:               hookProfileChanges{autoLoaded: []string{"dev"}},
+		},
+		{
+			name:           "Entering a mapped directory with a profile loaded by hand",
+			wantedProfiles: []string{"dev", "aws"},
+			loadedProfiles: []string{"dev"},
+			want:           hookProfileChanges{toLoad: []string{"aws"}, autoLoaded: []string{"aws"}},
+		},
+		{
+			name:               "Leaving a mapped directory with a profile loaded by hand",
+			loadedProfiles:     []string{"dev", "aws"},
+			autoLoadedProfiles: []string{"aws"},
+			want:               hookProfileChanges{toUnload: []string{"aws"}},
+		},
+		{
+			name:               "Profile loaded by hand in a mapped directory",
+			wantedProfiles:     []string{"dev"},
+			loadedProfiles:     []string{"dev", "db"},
+			autoLoadedProfiles: []string{"dev"},
+			want:               hookProfileChanges{autoLoaded: []string{"dev"}},
+		},
+		{
+			name:           "Unmapped directory",
+			loadedProfiles: []string{"db"},
+			want:           hookProfileChanges{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getHookProfileChanges(tt.wantedProfiles, tt.loadedProfiles, tt.autoLoadedProfiles)
+			assert.Equal(t, tt.want, got, "getHookProfileChanges()")
+		})
+	}
+}
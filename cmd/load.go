@@ -5,6 +5,7 @@ import (
 	"envManager/helper"
 	"envManager/secretsStorage"
 	"github.com/spf13/cobra"
+	"gopkg.in/errgo.v2/fmt/errors"
 	"os"
 	"slices"
 	"strings"
@@ -18,30 +19,48 @@ var loadCmd = &cobra.Command{
 If called without profiles, the directory mapping for the current working directory will be loaded.`,
 	Run:               runLoad,
 	ValidArgsFunction: CompleteProfiles,
-	PreRun:            InitConfig,
+	PreRun:            InitConfigForStatements,
 }
 
 func runLoad(_ *cobra.Command, args []string) {
-	registry := secretsStorage.GetRegistry()
+	dialect, err := getShellDialect()
+	cobra.CheckErr(err)
 	env := environment.NewEnvironment()
 	env.Load()
 
-	if len(args) == 0 {
+	profilesToLoad, err := resolveProfiles(args)
+	cobra.CheckErr(err)
+	cobra.CheckErr(loadProfiles(&env, profilesToLoad))
+	cobra.CheckErr(writeStatements(&env, dialect))
+}
+
+// resolveProfiles selects the given profiles and all their dependencies for
+// loading. If no profiles are given, the directory mapping for the current
+// working directory is used instead.
+func resolveProfiles(names []string) ([]string, error) {
+	registry := secretsStorage.GetRegistry()
+
+	if len(names) == 0 {
 		workingDir, err := os.Getwd()
-		cobra.CheckErr(err)
-		if registry.HasDirectoryMapping(workingDir) {
-			args, err = registry.GetDirectoryMapping(workingDir)
-			cobra.CheckErr(err)
-		} else {
-			cobra.CheckErr("No profiles specified and no mapping for this path found")
+		if err != nil {
+			return nil, err
+		}
+		if !registry.HasDirectoryMapping(workingDir) {
+			return nil, errors.New("No profiles specified and no mapping for this path found")
+		}
+		names, err = registry.GetDirectoryMapping(workingDir)
+		if err != nil {
+			return nil, err
 		}
 	}
 
 	var profilesToLoad []string
-	for _, name := range args {
+	for _, name := range names {
 		// get the profile from the registry
 		profile, err := registry.GetProfile(name)
-		cobra.CheckErr(err)
+		if err != nil {
+			return nil, err
+		}
 
 		if slices.Contains(profilesToLoad, name) {
 			// this profile is already loaded, thus all its dependencies are
@@ -52,23 +71,75 @@ func runLoad(_ *cobra.Command, args []string) {
 		profilesToLoad = append(profilesToLoad, name)
 		// get the dependencies of this profile
 		dependencies, err := profile.GetDependencies(profilesToLoad)
-		cobra.CheckErr(err)
+		if err != nil {
+			return nil, err
+		}
 		// select the dependencies for loading too
 		profilesToLoad = append(profilesToLoad, dependencies...)
 	}
+	return profilesToLoad, nil
+}
 
-	// load every profile selected for loading
-	for _, name := range profilesToLoad {
+// loadProfiles adds every profile selected for loading to the environment and
+// records them in the variable holding the loaded profiles. Dependencies are added
+// before the profiles depending on them, so templates can use their variables.
+func loadProfiles(env *environment.Environment, profilesToLoad []string) error {
+	registry := secretsStorage.GetRegistry()
+	// the files of closed shells are removed on the next invocation
+	if err := environment.CleanStaleSessionDirs(); err != nil {
+		return err
+	}
+	orderedProfiles, err := orderByDependencies(profilesToLoad)
+	if err != nil {
+		return err
+	}
+	for _, name := range orderedProfiles {
 		profile, err := registry.GetProfile(name)
-		cobra.CheckErr(err)
-		err = profile.AddToEnvironment(&env)
-		cobra.CheckErr(err)
+		if err != nil {
+			return err
+		}
+		err = profile.AddToEnvironment(env)
+		if err != nil {
+			return err
+		}
+	}
+	newEnvManagerLoadedValue := helper.SliceStringUnique(append(getLoadedProfiles(env), profilesToLoad...))
+	return env.Set(envManagerLoadedProfilesName, strings.Join(newEnvManagerLoadedValue, ","))
+}
+
+// orderByDependencies orders the profiles so every profile comes after its
+// dependencies. Dependencies which are not in names are ignored. In a cycle of
+// profiles depending on each other, the profile given first comes last.
+func orderByDependencies(names []string) ([]string, error) {
+	registry := secretsStorage.GetRegistry()
+	var ordered []string
+	var visiting []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		if slices.Contains(ordered, name) || slices.Contains(visiting, name) {
+			return nil
+		}
+		profile, err := registry.GetProfile(name)
+		if err != nil {
+			return err
+		}
+		visiting = append(visiting, name)
+		for _, dependency := range profile.DependsOn {
+			if slices.Contains(names, dependency) {
+				if err := visit(dependency); err != nil {
+					return err
+				}
+			}
+		}
+		ordered = append(ordered, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
 	}
-	loadedProfiles := strings.Split(env.GetCurrent(envManagerLoadedProfilesName, ""), ",")
-	newEnvManagerLoadedValue := helper.SliceStringUnique(append(loadedProfiles, profilesToLoad...))
-	newEnvManagerLoadedValue = helper.SliceStringRemove("", newEnvManagerLoadedValue)
-	_ = env.Set(envManagerLoadedProfilesName, strings.Join(newEnvManagerLoadedValue, ","))
-	print(env.WriteStatements())
+	return ordered, nil
 }
 
 func init() {
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"envManager/secretsStorage"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_orderByDependencies(t *testing.T) {
+	registry := secretsStorage.GetRegistry()
+	addProfile := func(name string, dependsOn ...string) {
+		_ = registry.AddProfile(name, secretsStorage.Profile{DependsOn: dependsOn})
+	}
+	addProfile("orderA", "orderB")
+	addProfile("orderB", "orderC")
+	addProfile("orderC")
+	addProfile("cycleA", "cycleB")
+	addProfile("cycleB", "cycleA")
+	addProfile("selfReference", "selfReference")
+
+	tests := []struct {
+		name    string
+		names   []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "Transitive chain in reverse order", names: []string{"orderC", "orderB", "orderA"}, want: []string{"orderC", "orderB", "orderA"}},
+		{name: "Transitive chain", names: []string{"orderA", "orderB", "orderC"}, want: []string{"orderC", "orderB", "orderA"}},
+		{name: "Dependency not selected", names: []string{"orderB"}, want: []string{"orderB"}},
+		{name: "Dependency of dependency not selected", names: []string{"orderA", "orderB"}, want: []string{"orderB", "orderA"}},
+		{name: "Cycle", names: []string{"cycleA", "cycleB"}, want: []string{"cycleB", "cycleA"}},
+		{name: "Cycle given in other order", names: []string{"cycleB", "cycleA"}, want: []string{"cycleA", "cycleB"}},
+		{name: "Profile depending on itself", names: []string{"selfReference"}, want: []string{"selfReference"}},
+		{name: "Duplicate profile", names: []string{"orderC", "orderC"}, want: []string{"orderC"}},
+		{name: "Unknown profile", names: []string{"orderC", "unknownProfile"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := orderByDependencies(tt.names)
+			if tt.wantErr {
+				assert.Error(t, err, "orderByDependencies()")
+				return
+			}
+			if assert.NoError(t, err, "orderByDependencies()") {
+				assert.Equal(t, tt.want, got, "orderByDependencies()")
+			}
+		})
+	}
+}
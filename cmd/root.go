@@ -1,18 +1,29 @@
 package cmd
 
 import (
+	"envManager/environment"
+	"envManager/helper"
 	"envManager/secretsStorage"
 	"fmt"
 	"github.com/spf13/cobra"
 	"os"
 	"path"
+	"strings"
 )
 
 var flagConfigFile string
+var flagShell string
 
 // The name of the environment variable containing the loaded profile names
 const envManagerLoadedProfilesName = "ENVMANAGER_LOADED"
 
+// The name of the environment variable which selects the shell dialect if --shell is not given
+const envManagerShellName = "ENVMANAGER_SHELL"
+
+// The name of the environment variable containing the ignored config files which were already warned about in this
+// shell session
+const envManagerWarnedConfigsName = "ENVMANAGER_WARNED_CONFIGS"
+
 var version = "unknown"
 
 var homeDir string
@@ -45,10 +56,28 @@ func init() {
 	)
 	_ = rootCmd.MarkPersistentFlagFilename("config", "yml")
 	_ = rootCmd.PersistentFlags().MarkDeprecated("config", "since the introduction of directory-aware loading.")
+	rootCmd.PersistentFlags().StringVar(
+		&flagShell,
+		"shell",
+		"",
+		"Shell to write the statements for (one of "+strings.Join(environment.GetDialectNames(), ", ")+
+			"). Defaults to $"+envManagerShellName+" or is detected from $SHELL.",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&helper.GetInput().PasswordStdin,
+		"password-stdin",
+		false,
+		"Read the password of the storages from stdin instead of prompting for it.",
+	)
+	_ = rootCmd.RegisterFlagCompletionFunc("shell", func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return helper.Completion(environment.GetDialectNames(), nil, toComplete), cobra.ShellCompDirectiveNoFileComp
+	})
 }
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
+// initConfig reads in config file and ENV variables if set. Returns the discovered
+// config files which were skipped because they are not allowed (or were modified
+// since), denied files are skipped silently.
+func initConfig() []string {
 	config := secretsStorage.NewConfiguration()
 	err := config.LoadFromFile(flagConfigFile)
 	cobra.CheckErr(err)
@@ -59,13 +88,26 @@ func initConfig() {
 
 	// use helper function to find all config files upward from here
 	configFiles := discoverConfigFiles(dir, flagConfigFile)
+	trustStore, err := loadTrustStore()
+	cobra.CheckErr(err)
 
+	var untrustedFiles []string
 	for i, configFile := range configFiles {
 		if configFile == flagConfigFile {
 			// do not merge the main config file as it was loaded with config.LoadFromFile()
 			continue
 		}
-		err := config.MergeConfigFile(configFiles[i])
+		// anybody can put a config file into a directory, e.g. into a cloned repository, so only allowed files are
+		// merged
+		status, err := trustStore.GetStatus(configFile)
+		cobra.CheckErr(err)
+		if status != secretsStorage.TrustStatusTrusted {
+			if status != secretsStorage.TrustStatusDenied {
+				untrustedFiles = append(untrustedFiles, configFile)
+			}
+			continue
+		}
+		err = config.MergeConfigFile(configFiles[i])
 		if err != nil {
 			_, _ = fmt.Fprintln(os.Stderr, "Failed to merge configuration.\nAlready processed config files:")
 			_, _ = fmt.Fprint(os.Stderr, formatList(configFiles[0:i], "\t- ", "\n", "\t<none>\n"))
@@ -91,8 +133,14 @@ func initConfig() {
 		cobra.CheckErr(err)
 	}
 
-	for mappingPath, profiles := range config.DirectoryMapping {
-		err := registry.AddDirectoryMapping(mappingPath, profiles)
+	for mappingPath, mapping := range config.DirectoryMapping {
+		var err error
+		if mapping.Exact {
+			err = registry.AddExactDirectoryMapping(mappingPath, mapping.Profiles)
+		} else {
+			err = registry.AddDirectoryMapping(mappingPath, mapping.Profiles)
+		}
 		cobra.CheckErr(err)
 	}
+	return untrustedFiles
 }
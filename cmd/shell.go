@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"envManager/environment"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+var flagShellPrompt bool
+
+// shellCmd represents the shell command
+var shellCmd = &cobra.Command{
+	Use:   "shell [profile]...",
+	Short: "Start a subshell with profiles loaded",
+	Long: `Start $SHELL as a subshell with one or more profiles loaded. Leaving the subshell
+(e.g. with exit) drops every variable loaded into it. If called without profiles,
+the directory mapping for the current working directory will be loaded.`,
+	Run:               runShell,
+	ValidArgsFunction: CompleteProfiles,
+	PreRun:            InitConfig,
+}
+
+func runShell(_ *cobra.Command, args []string) {
+	shellPath := os.Getenv("SHELL")
+	if shellPath == "" {
+		shellPath = "/bin/sh"
+	}
+	env := environment.NewEnvironment()
+	env.Load()
+	// the files of the subshell are removed when it exits
+	env.SetSessionOwner(os.Getpid())
+
+	profilesToLoad, err := resolveProfiles(args)
+	cobra.CheckErr(err)
+	cobra.CheckErr(loadProfiles(&env, profilesToLoad))
+
+	var shellArgs []string
+	if flagShellPrompt {
+		shellArgs = addPromptHint(&env, environment.DetectDialect(shellPath), profilesToLoad)
+	}
+
+	subshell := exec.Command(shellPath, shellArgs...)
+	subshell.Stdin = os.Stdin
+	subshell.Stdout = os.Stdout
+	subshell.Stderr = os.Stderr
+	subshell.Env = env.Environ()
+
+	// the terminal sends signals like Ctrl+C to the subshell and envManager, only the subshell should react on them.
+	// The signals are caught instead of ignored, as ignored signals would be inherited by the subshell.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGQUIT, syscall.SIGTSTP)
+	defer signal.Stop(signals)
+
+	err = subshell.Run()
+	cobra.CheckErr(environment.RemoveSessionDir(os.Getpid()))
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	cobra.CheckErr(err)
+}
+
+// addPromptHint changes the environment of the subshell, so its prompt names the
+// loaded profiles. Returns the arguments to start the shell with.
+func addPromptHint(env *environment.Environment, dialect environment.ShellDialect, profiles []string) []string {
+	hint := fmt.Sprintf("(envManager: %s) ", strings.Join(profiles, ","))
+	switch dialect.Name() {
+	// PS1 is usually not exported, so it is unknown here. The shell keeps it if no rc file sets it.
+	case "bash":
+		_ = env.Set("PS1", hint+env.GetCurrent("PS1", `\u@\h:\w\$ `))
+	case "sh":
+		_ = env.Set("PS1", hint+env.GetCurrent("PS1", "$ "))
+	case "zsh":
+		_ = env.Set("PS1", hint+env.GetCurrent("PS1", "%n@%m %1~ %# "))
+	case "fish":
+		// fish has no prompt variable, wrap the prompt function on startup instead
+		return []string{
+			"--init-command",
+			"functions --copy fish_prompt __envManager_fish_prompt; " +
+				"function fish_prompt; printf '%s' " + environment.QuoteFish(hint) + "; __envManager_fish_prompt; end",
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(shellCmd)
+	shellCmd.Flags().BoolVar(&flagShellPrompt, "prompt", false, "Name the loaded profiles in the prompt of the subshell (bash, zsh and fish)")
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"envManager/environment"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_addPromptHint(t *testing.T) {
+	tests := []struct {
+		name      string
+		shellPath string
+		// currentPS1 is exported before the environment is loaded, if not empty. Otherwise the environment stays empty.
+		currentPS1 string
+		profiles   []string
+		wantPS1    string
+		wantArgs   []string
+	}{
+		{
+			name:      "bash without PS1",
+			shellPath: "/bin/bash",
+			profiles:  []string{"dev"},
+			wantPS1:   `(envManager: dev) \u@\h:\w\$ `,
+		},
+		{
+			name:       "bash with exported PS1",
+			shellPath:  "/bin/bash",
+			currentPS1: `\w > `,
+			profiles:   []string{"dev", "aws"},
+			wantPS1:    `(envManager: dev,aws) \w > `,
+		},
+		{
+			name:      "zsh without PS1",
+			shellPath: "/usr/bin/zsh",
+			profiles:  []string{"dev"},
+			wantPS1:   "(envManager: dev) %n@%m %1~ %# ",
+		},
+		{
+			name:       "zsh with exported PS1",
+			shellPath:  "/usr/bin/zsh",
+			currentPS1: "%~ %# ",
+			profiles:   []string{"dev"},
+			wantPS1:    "(envManager: dev) %~ %# ",
+		},
+		{
+			name:      "fish wraps the prompt function",
+			shellPath: "/usr/bin/fish",
+			profiles:  []string{"dev", "it's"},
+			wantArgs: []string{
+				"--init-command",
+				"functions --copy fish_prompt __envManager_fish_prompt; " +
+					`function fish_prompt; printf '%s' '(envManager: dev,it\'s) '; __envManager_fish_prompt; end`,
+			},
+		},
+		{
+			name:      "Unsupported shell",
+			shellPath: "/bin/tcsh",
+			profiles:  []string{"dev"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := environment.NewEnvironment()
+			if tt.currentPS1 != "" {
+				t.Setenv("PS1", tt.currentPS1)
+				env.Load()
+			}
+			gotArgs := addPromptHint(&env, environment.DetectDialect(tt.shellPath), tt.profiles)
+			assert.Equal(t, tt.wantArgs, gotArgs, "addPromptHint() arguments")
+			gotPS1, isSet := env.Lookup("PS1")
+			if tt.wantPS1 == "" {
+				assert.Equal(t, tt.currentPS1, gotPS1, "PS1 must not be changed")
+				return
+			}
+			assert.True(t, isSet, "PS1 is set")
+			assert.Equal(t, tt.wantPS1, gotPS1, "PS1")
+		})
+	}
+}
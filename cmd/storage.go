@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// storageCmd represents the storage command
+var storageCmd = &cobra.Command{
+	Use:              "storage",
+	Short:            "Work with the content of your storages",
+	PersistentPreRun: InitConfig,
+}
+
+func init() {
+	rootCmd.AddCommand(storageCmd)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"envManager/secretsStorage"
+	"errors"
+	"filippo.io/age"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var flagEncryptRecipients []string
+var flagEncryptStorage string
+var flagEncryptInPlace bool
+
+// storageEncryptCmd represents the storage encrypt command
+var storageEncryptCmd = &cobra.Command{
+	Use:   "encrypt [file]",
+	Short: "Encrypt the values of a YAML or JSON file for the age storage adapter",
+	Long: `Encrypt all values of a YAML or JSON file with age, so it can be committed and read
+with the age storage adapter. Values which are already encrypted are kept, so you can
+add plain values to an encrypted file and encrypt it again.
+
+The recipients are given with --recipient or taken from the identity file of an age
+storage with --storage. Files ending with .json are written as JSON, all others as YAML.
+The result is printed unless --in-place is set.
+
+  envManager storage encrypt secrets.yml --storage repoSecrets --in-place`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		recipients, err := getEncryptRecipients()
+		cobra.CheckErr(err)
+
+		file := args[0]
+		data, err := os.ReadFile(file)
+		cobra.CheckErr(err)
+		isJSON := strings.EqualFold(filepath.Ext(file), ".json")
+		encrypted, err := secretsStorage.EncryptAgeDocument(data, isJSON, recipients)
+		cobra.CheckErr(err)
+
+		if !flagEncryptInPlace {
+			fmt.Print(string(encrypted))
+			return
+		}
+		fileInfo, err := os.Stat(file)
+		cobra.CheckErr(err)
+		cobra.CheckErr(os.WriteFile(file, encrypted, fileInfo.Mode().Perm()))
+		fmt.Printf("Encrypted %s\n", file)
+	},
+}
+
+// getEncryptRecipients returns the recipients given with --recipient and --storage
+func getEncryptRecipients() ([]age.Recipient, error) {
+	recipients, err := secretsStorage.ParseAgeRecipients(flagEncryptRecipients)
+	if err != nil {
+		return nil, err
+	}
+	if flagEncryptStorage != "" {
+		storagePtr, err := secretsStorage.GetRegistry().GetStorage(flagEncryptStorage)
+		if err != nil {
+			return nil, err
+		}
+		ageStorage, isAge := (*storagePtr).(*secretsStorage.Age)
+		if !isAge {
+			return nil, fmt.Errorf("storage %s is not an %s storage", flagEncryptStorage, secretsStorage.AgeTypeIdentifier)
+		}
+		storageRecipients, err := ageStorage.GetRecipients()
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, storageRecipients...)
+	}
+	if len(recipients) == 0 {
+		return nil, errors.New("at least one recipient is required, use --recipient or --storage")
+	}
+	return recipients, nil
+}
+
+func init() {
+	storageCmd.AddCommand(storageEncryptCmd)
+	storageEncryptCmd.Flags().StringArrayVarP(&flagEncryptRecipients, "recipient", "r", []string{}, "Encrypt for this age recipient (age1...), can be used multiple times")
+	storageEncryptCmd.Flags().StringVarP(&flagEncryptStorage, "storage", "s", "", "Encrypt for the identities of this age storage")
+	storageEncryptCmd.Flags().BoolVarP(&flagEncryptInPlace, "in-place", "i", false, "Overwrite the file instead of printing the result")
+	_ = storageEncryptCmd.RegisterFlagCompletionFunc("storage", CompleteStorages)
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+// storageLsCmd represents the storage ls command
+var storageLsCmd = &cobra.Command{
+	Use:   "ls [storage] [prefix]",
+	Short: "Lists the entries of a storage",
+	Long: `Lists the paths of all entries of a storage, or only those starting with prefix. The
+paths can be used as path of a profile. Not all storage adapters support listing.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		prefix := ""
+		if len(args) == 2 {
+			prefix = args[1]
+		}
+		entries, err := listEntries(args[0], prefix)
+		cobra.CheckErr(err)
+		for _, entry := range entries {
+			fmt.Println(entry)
+		}
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		switch len(args) {
+		case 0:
+			return CompleteStorages(cmd, args, toComplete)
+		case 1:
+			return CompleteEntryPaths(args[0], toComplete)
+		default:
+			return []string{}, cobra.ShellCompDirectiveNoFileComp
+		}
+	},
+}
+
+func init() {
+	storageCmd.AddCommand(storageLsCmd)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"envManager/helper"
+	"envManager/secretsStorage"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+// storageSetCmd represents the storage set command
+var storageSetCmd = &cobra.Command{
+	Use:   "set [storage] [path] [attribute=value]...",
+	Short: "Creates or updates an entry of a storage",
+	Long: `Sets the attributes of the entry at path, creating the entry if it does not exist. Attributes
+which are not given are kept. An attribute without =value is asked for with hidden input, so
+secrets do not end up in the shell history:
+
+  envManager storage set keepass01 aws/prod Password UserName=deploy
+
+Every profile using the entry gets the new values when it is loaded the next time. Not all
+storage adapters support writing.`,
+	Args: cobra.MinimumNArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		storagePtr, err := secretsStorage.GetRegistry().GetStorage(args[0])
+		cobra.CheckErr(err)
+		writer, isWriter := (*storagePtr).(secretsStorage.EntryWriter)
+		if !isWriter {
+			cobra.CheckErr(fmt.Errorf("the storage %s cannot write entries", args[0]))
+		}
+		entry, err := parseEntryAttributes(args[2:])
+		cobra.CheckErr(err)
+		cobra.CheckErr(writer.SetEntry(args[1], entry))
+		fmt.Printf("Updated %s in %s\n", args[1], args[0])
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		switch len(args) {
+		case 0:
+			return CompleteStorages(cmd, args, toComplete)
+		case 1:
+			return CompleteEntryPaths(args[0], toComplete)
+		default:
+			return []string{}, cobra.ShellCompDirectiveNoFileComp
+		}
+	},
+}
+
+// parseEntryAttributes creates an entry from arguments like attribute=value. The value of an argument without = is
+// asked for with hidden input.
+func parseEntryAttributes(args []string) (*secretsStorage.Entry, error) {
+	entry := secretsStorage.NewEntry()
+	for _, arg := range args {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if name == "" {
+			return nil, errors.New("attribute names must not be empty, use attribute=value")
+		}
+		if !hasValue {
+			var err error
+			value, err = helper.GetInput().PromptPassword("Enter "+name, '*')
+			if errors.Is(err, helper.ErrNoTerminal) {
+				return nil, fmt.Errorf("no terminal available to ask for %s, use %s=value", name, name)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err := entry.SetAttribute(name, value); err != nil {
+			return nil, err
+		}
+	}
+	return &entry, nil
+}
+
+func init() {
+	storageCmd.AddCommand(storageSetCmd)
+}
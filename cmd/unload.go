@@ -16,11 +16,12 @@ var unloadCmd = &cobra.Command{
 	Long:              `Unload one or more profiles from this shell's environment`,
 	Run:               runUnload,
 	ValidArgsFunction: CompleteProfiles,
-	PreRun:            InitConfig,
+	PreRun:            InitConfigForStatements,
 }
 
 func runUnload(cmd *cobra.Command, args []string) {
-	registry := secretsStorage.GetRegistry()
+	dialect, err := getShellDialect()
+	cobra.CheckErr(err)
 	env := environment.NewEnvironment()
 	env.Load()
 	loadedProfiles := strings.Split(env.GetCurrent(envManagerLoadedProfilesName, ""), ",")
@@ -32,16 +33,30 @@ func runUnload(cmd *cobra.Command, args []string) {
 		// no --all flag and no profile name specified
 		fmt.Println("You must specify at least one profile to unload")
 	}
-	for i := 0; i < len(args); i++ {
-		profile, err := registry.GetProfile(args[i])
-		cobra.CheckErr(err)
-		err = profile.RemoveFromEnvironment(&env)
-		cobra.CheckErr(err)
-		loadedProfiles = helper.SliceStringRemove(args[i], loadedProfiles)
+	cobra.CheckErr(unloadProfiles(&env, args))
+	cobra.CheckErr(writeStatements(&env, dialect))
+}
+
+// unloadProfiles removes the given profiles from the environment and from the
+// variable holding the loaded profiles.
+func unloadProfiles(env *environment.Environment, profilesToUnload []string) error {
+	registry := secretsStorage.GetRegistry()
+	if err := environment.CleanStaleSessionDirs(); err != nil {
+		return err
+	}
+	loadedProfiles := getLoadedProfiles(env)
+	for _, name := range profilesToUnload {
+		profile, err := registry.GetProfile(name)
+		if err != nil {
+			return err
+		}
+		err = profile.RemoveFromEnvironment(env)
+		if err != nil {
+			return err
+		}
+		loadedProfiles = helper.SliceStringRemove(name, loadedProfiles)
 	}
-	loadedProfiles = helper.SliceStringRemove("", loadedProfiles)
-	_ = env.Set(envManagerLoadedProfilesName, strings.Join(loadedProfiles, ","))
-	print(env.WriteStatements())
+	return env.Set(envManagerLoadedProfilesName, strings.Join(loadedProfiles, ","))
 }
 
 func init() {
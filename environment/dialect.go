@@ -0,0 +1,199 @@
+package environment
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ShellDialect renders the statements which set and unset environment variables in the syntax of a specific shell.
+// Statements of all dialects can be joined with a semicolon.
+type ShellDialect interface {
+	//Name returns the identifier of the dialect, as accepted by GetDialect
+	Name() string
+	//SetStatement returns a statement which exports the variable key with the given value. The value must be quoted
+	//in a way that the shell takes it literally.
+	SetStatement(key string, value string) string
+	//UnsetStatement returns a statement which removes the variable key from the environment
+	UnsetStatement(key string) string
+	//WarningStatement returns a statement which prints message to stderr. The message must be quoted like the values
+	//of SetStatement.
+	WarningStatement(message string) string
+}
+
+// dialects holds all known dialects by their name
+var dialects = map[string]ShellDialect{
+	"bash":       posixDialect{name: "bash"},
+	"zsh":        posixDialect{name: "zsh"},
+	"sh":         posixDialect{name: "sh"},
+	"fish":       fishDialect{},
+	"nushell":    nushellDialect{},
+	"powershell": powershellDialect{},
+	"tcsh":       tcshDialect{},
+}
+
+// dialectAliases maps names of shell executables to the name of the dialect they speak
+var dialectAliases = map[string]string{
+	"ash":            "sh",
+	"dash":           "sh",
+	"ksh":            "sh",
+	"mksh":           "sh",
+	"nu":             "nushell",
+	"pwsh":           "powershell",
+	"pwsh.exe":       "powershell",
+	"powershell.exe": "powershell",
+	"csh":            "tcsh",
+}
+
+// GetDialect returns the dialect with the given name. The names of shell executables (e.g. pwsh for powershell) are
+// accepted as well. Will return an error if the name is unknown.
+func GetDialect(name string) (ShellDialect, error) {
+	if alias, exists := dialectAliases[name]; exists {
+		name = alias
+	}
+	dialect, exists := dialects[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown shell %s, supported shells are %s", name, strings.Join(GetDialectNames(), ", "))
+	}
+	return dialect, nil
+}
+
+// GetDialectNames returns the sorted names of all known dialects
+func GetDialectNames() []string {
+	return sortedKeys(dialects)
+}
+
+// DetectDialect determines the dialect from the path of a shell executable, as found in $SHELL. If the shell is
+// unknown or shellPath is empty, the bash dialect is returned.
+func DetectDialect(shellPath string) ShellDialect {
+	// login shells are sometimes reported with a leading dash
+	name := strings.TrimPrefix(filepath.Base(shellPath), "-")
+	dialect, err := GetDialect(name)
+	if err != nil {
+		return dialects["bash"]
+	}
+	return dialect
+}
+
+// posixDialect renders statements for bash, zsh and other POSIX compatible shells
+type posixDialect struct {
+	name string
+}
+
+func (d posixDialect) Name() string {
+	return d.name
+}
+
+func (d posixDialect) SetStatement(key string, value string) string {
+	return fmt.Sprintf("export %s=%s", key, QuotePosix(value))
+}
+
+func (d posixDialect) UnsetStatement(key string) string {
+	return "unset " + key
+}
+
+func (d posixDialect) WarningStatement(message string) string {
+	// echo interprets backslashes in some shells, printf does not
+	return fmt.Sprintf("printf '%%s\\n' %s >&2", QuotePosix(message))
+}
+
+// fishDialect renders statements for the fish shell
+type fishDialect struct{}
+
+func (d fishDialect) Name() string {
+	return "fish"
+}
+
+func (d fishDialect) SetStatement(key string, value string) string {
+	return fmt.Sprintf("set -gx %s %s", key, QuoteFish(value))
+}
+
+func (d fishDialect) UnsetStatement(key string) string {
+	return "set -e " + key
+}
+
+func (d fishDialect) WarningStatement(message string) string {
+	return fmt.Sprintf("printf '%%s\\n' %s >&2", QuoteFish(message))
+}
+
+// nushellDialect renders statements for nushell
+type nushellDialect struct{}
+
+func (d nushellDialect) Name() string {
+	return "nushell"
+}
+
+func (d nushellDialect) SetStatement(key string, value string) string {
+	return fmt.Sprintf("load-env {%s: %s}", key, quoteNushell(value))
+}
+
+func (d nushellDialect) UnsetStatement(key string) string {
+	return "hide-env --ignore-errors " + key
+}
+
+func (d nushellDialect) WarningStatement(message string) string {
+	return "print --stderr " + quoteNushell(message)
+}
+
+// quoteNushell quotes value as raw string. Raw strings (r#'...'#) take everything literally, they only end at a quote
+// followed by the same amount of hashes used to start them. Use one more hash than any quote in the value is followed
+// by.
+func quoteNushell(value string) string {
+	hashes := "#"
+	for strings.Contains(value, "'"+hashes) {
+		hashes += "#"
+	}
+	return fmt.Sprintf("r%s'%s'%s", hashes, value, hashes)
+}
+
+// powershellDialect renders statements for PowerShell
+type powershellDialect struct{}
+
+func (d powershellDialect) Name() string {
+	return "powershell"
+}
+
+func (d powershellDialect) SetStatement(key string, value string) string {
+	return fmt.Sprintf("$env:%s = %s", key, quotePowershell(value))
+}
+
+func (d powershellDialect) UnsetStatement(key string) string {
+	return fmt.Sprintf("Remove-Item -ErrorAction SilentlyContinue Env:%s", key)
+}
+
+func (d powershellDialect) WarningStatement(message string) string {
+	return fmt.Sprintf("[Console]::Error.WriteLine(%s)", quotePowershell(message))
+}
+
+// quotePowershell quotes value in single quotes. PowerShell treats the typographic single quotes as quotes as well, all
+// of them are escaped by doubling them.
+func quotePowershell(value string) string {
+	return "'" + strings.NewReplacer("'", "''", "‘", "‘‘", "’", "’’", "‚", "‚‚", "‛", "‛‛").Replace(value) + "'"
+}
+
+// tcshDialect renders statements for tcsh and csh
+type tcshDialect struct{}
+
+func (d tcshDialect) Name() string {
+	return "tcsh"
+}
+
+func (d tcshDialect) SetStatement(key string, value string) string {
+	return fmt.Sprintf("setenv %s %s", key, quoteTcsh(value))
+}
+
+func (d tcshDialect) UnsetStatement(key string) string {
+	return "unsetenv " + key
+}
+
+func (d tcshDialect) WarningStatement(message string) string {
+	// tcsh cannot redirect stdout to stderr alone, so it is written to the device
+	return fmt.Sprintf("printf '%%s\\n' %s > /dev/stderr", quoteTcsh(message))
+}
+
+// quoteTcsh quotes value in single quotes. Like in POSIX shells a single quote is written by leaving the quoted string.
+// Additionally, history substitution happens within single quotes, so ! must be escaped, and newlines must be escaped
+// to not end the statement.
+func quoteTcsh(value string) string {
+	return "'" + strings.NewReplacer("'", `'\''`, "!", `\!`, "\n", "\\\n").Replace(value) + "'"
+}
@@ -0,0 +1,158 @@
+package environment
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestGetDialect(t *testing.T) {
+	tests := []struct {
+		name     string
+		shell    string
+		wantName string
+		wantErr  bool
+	}{
+		{name: "Dialect name", shell: "fish", wantName: "fish", wantErr: false},
+		{name: "Executable alias", shell: "pwsh", wantName: "powershell", wantErr: false},
+		{name: "Nushell executable", shell: "nu", wantName: "nushell", wantErr: false},
+		{name: "csh executable", shell: "csh", wantName: "tcsh", wantErr: false},
+		{name: "Unknown shell", shell: "cmd.exe", wantName: "", wantErr: true},
+		{name: "Empty name", shell: "", wantName: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetDialect(tt.shell)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetDialect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.Name() != tt.wantName {
+				t.Errorf("GetDialect() = %v, want %v", got.Name(), tt.wantName)
+			}
+		})
+	}
+}
+
+func TestDetectDialect(t *testing.T) {
+	tests := []struct {
+		name      string
+		shellPath string
+		want      string
+	}{
+		{name: "bash", shellPath: "/bin/bash", want: "bash"},
+		{name: "zsh", shellPath: "/usr/bin/zsh", want: "zsh"},
+		{name: "fish", shellPath: "/usr/local/bin/fish", want: "fish"},
+		{name: "nushell", shellPath: "/home/john/.cargo/bin/nu", want: "nushell"},
+		{name: "PowerShell", shellPath: "/usr/bin/pwsh", want: "powershell"},
+		{name: "tcsh", shellPath: "/bin/tcsh", want: "tcsh"},
+		{name: "dash", shellPath: "/bin/dash", want: "sh"},
+		{name: "Login shell", shellPath: "-zsh", want: "zsh"},
+		{name: "Unknown shell falls back to bash", shellPath: "/bin/unknown", want: "bash"},
+		{name: "Empty path falls back to bash", shellPath: "", want: "bash"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectDialect(tt.shellPath); got.Name() != tt.want {
+				t.Errorf("DetectDialect() = %v, want %v", got.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestShellDialect_Statements(t *testing.T) {
+	tests := []struct {
+		dialect   string
+		value     string
+		wantSet   string
+		wantUnset string
+	}{
+		{dialect: "bash", value: `it's`, wantSet: `export KEY='it'\''s'`, wantUnset: "unset KEY"},
+		{dialect: "zsh", value: `$HOME`, wantSet: `export KEY='$HOME'`, wantUnset: "unset KEY"},
+		{dialect: "fish", value: `it's a \ `, wantSet: `set -gx KEY 'it\'s a \\ '`, wantUnset: "set -e KEY"},
+		{dialect: "nushell", value: `it's`, wantSet: `load-env {KEY: r#'it's'#}`, wantUnset: "hide-env --ignore-errors KEY"},
+		{dialect: "nushell", value: `a'#b`, wantSet: `load-env {KEY: r##'a'#b'##}`, wantUnset: "hide-env --ignore-errors KEY"},
+		{dialect: "powershell", value: `it's $HOME`, wantSet: `$env:KEY = 'it''s $HOME'`, wantUnset: "Remove-Item -ErrorAction SilentlyContinue Env:KEY"},
+		{dialect: "powershell", value: "it’s", wantSet: "$env:KEY = 'it’’s'", wantUnset: "Remove-Item -ErrorAction SilentlyContinue Env:KEY"},
+		{dialect: "tcsh", value: "it's!\n", wantSet: "setenv KEY 'it'\\''s\\!\\\n'", wantUnset: "unsetenv KEY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			dialect, err := GetDialect(tt.dialect)
+			if err != nil {
+				t.Fatalf("GetDialect() returned error %v", err)
+			}
+			if got := dialect.SetStatement("KEY", tt.value); got != tt.wantSet {
+				t.Errorf("SetStatement() = %v, want %v", got, tt.wantSet)
+			}
+			if got := dialect.UnsetStatement("KEY"); got != tt.wantUnset {
+				t.Errorf("UnsetStatement() = %v, want %v", got, tt.wantUnset)
+			}
+		})
+	}
+}
+
+func TestShellDialect_WarningStatement(t *testing.T) {
+	tests := []struct {
+		dialect string
+		want    string
+	}{
+		{dialect: "bash", want: `printf '%s\n' 'it'\''s' >&2`},
+		{dialect: "fish", want: `printf '%s\n' 'it\'s' >&2`},
+		{dialect: "nushell", want: `print --stderr r#'it's'#`},
+		{dialect: "powershell", want: `[Console]::Error.WriteLine('it''s')`},
+		{dialect: "tcsh", want: `printf '%s\n' 'it'\''s' > /dev/stderr`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			dialect, _ := GetDialect(tt.dialect)
+			if got := dialect.WarningStatement("it's"); got != tt.want {
+				t.Errorf("WarningStatement() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestShellDialect_evalRoundTrip evaluates the statements of every dialect in its shell, if that shell is installed.
+func TestShellDialect_evalRoundTrip(t *testing.T) {
+	shells := map[string][]string{
+		"bash":       {"bash", "--norc", "-c"},
+		"zsh":        {"zsh", "-f", "-c"},
+		"sh":         {"sh", "-c"},
+		"fish":       {"fish", "--no-config", "-c"},
+		"nushell":    {"nu", "--no-config-file", "-c"},
+		"powershell": {"pwsh", "-NoProfile", "-NonInteractive", "-Command"},
+		"tcsh":       {"tcsh", "-f", "-c"},
+	}
+	value := "pa'ss\"w$(id)`id`\\\\\\ör'#d;|&*!\n% {x} ’"
+	for dialectName, command := range shells {
+		t.Run(dialectName, func(t *testing.T) {
+			if _, err := exec.LookPath(command[0]); err != nil {
+				t.Skipf("%s is not installed", command[0])
+			}
+			dialect, _ := GetDialect(dialectName)
+			e := NewEnvironment()
+			_ = e.Set("SECRET", value)
+			_ = e.Unset("EDITOR")
+			// printenv works the same in all shells, nushell needs ^ to call an external command
+			printCommand := "printenv SECRET"
+			if dialectName == "nushell" {
+				printCommand = "^printenv SECRET"
+			}
+			script := dialect.WarningStatement(value) + ";" + e.WriteStatementsFor(dialect) + ";" + printCommand
+			args := append(command[1:], script)
+			var stderr strings.Builder
+			shell := exec.Command(command[0], args...)
+			shell.Stderr = &stderr
+			got, err := shell.Output()
+			if err != nil {
+				t.Fatalf("Evaluating %q failed with %v", script, err)
+			}
+			if strings.TrimSuffix(string(got), "\n") != value {
+				t.Errorf("Shell got %q, want %q", string(got), value)
+			}
+			if strings.TrimSuffix(stderr.String(), "\n") != value {
+				t.Errorf("Shell printed %q to stderr, want %q", stderr.String(), value)
+			}
+		})
+	}
+}
@@ -1,9 +1,8 @@
 package environment
 
 import (
-	"errors"
-	"fmt"
 	"os"
+	"slices"
 	"strings"
 )
 
@@ -16,14 +15,22 @@ type Environment struct {
 	addVars map[string]string
 	//delVars holds the variable names to remove with WriteStatements (will generate unset value statements)
 	delVars map[string]bool
+	//layers holds the values overwritten by Push for every variable, see PreviousValuesVariableName
+	layers map[string][]layer
+	//listChanges holds the items added to list variables by AddToList, see ListsVariableName
+	listChanges map[string][]listChange
+	//sessionOwner is the pid of the process owning the session directory, see SetSessionOwner
+	sessionOwner int
 }
 
 // NewEnvironment creates a new Environment object and initializes the fields with empty maps / slices
 func NewEnvironment() Environment {
 	return Environment{
-		current: map[string]string{},
-		addVars: map[string]string{},
-		delVars: map[string]bool{},
+		current:     map[string]string{},
+		addVars:     map[string]string{},
+		delVars:     map[string]bool{},
+		layers:      map[string][]layer{},
+		listChanges: map[string][]listChange{},
 	}
 }
 
@@ -34,6 +41,8 @@ func (e *Environment) Load() {
 		parts := strings.Split(element, "=")
 		e.current[parts[0]] = strings.Join(parts[1:], "=")
 	}
+	e.loadLayers()
+	e.loadListChanges()
 }
 
 // GetCurrent retrieves a currently set environment variable by the given key.
@@ -46,34 +55,86 @@ func (e *Environment) GetCurrent(key string, defaultValue string) string {
 	return value
 }
 
+// Lookup retrieves the value a variable will have after the statements are applied, taking pending Set and Unset
+// calls into account. The boolean reports if the variable will be set at all.
+func (e *Environment) Lookup(key string) (string, bool) {
+	if value, exists := e.addVars[key]; exists {
+		return value, true
+	}
+	if e.delVars[key] {
+		return "", false
+	}
+	value, exists := e.current[key]
+	return value, exists
+}
+
 // Set adds an environment variable with given key and value to the list of variables to set. Call WriteStatements to
-// create export statements consumable by a shell.
+// create export statements consumable by a shell. Will return an error if the key is not a valid variable name.
 func (e *Environment) Set(key string, value string) error {
-	if key == "" {
-		return errors.New("key must not be empty")
+	if err := ValidateVariableName(key); err != nil {
+		return err
 	}
+	// a pending Unset would remove the variable again
+	delete(e.delVars, key)
 	e.addVars[key] = value
 	return nil
 }
 
 // Unset removes an already set environment variable. Also removes it from the list of variables which will be set.
+// Will return an error if the key is not a valid variable name.
 func (e *Environment) Unset(key string) error {
-	if key == "" {
-		return errors.New("key must not be empty")
+	if err := ValidateVariableName(key); err != nil {
+		return err
 	}
 	delete(e.addVars, key)
 	e.delVars[key] = true
 	return nil
 }
 
-// WriteStatements writes a list of export and unset statements to update the environment of a shell.
+// Environ returns the environment after applying the pending Set and Unset calls in the form of os.Environ, e.g. to
+// start a child process with it.
+func (e *Environment) Environ() []string {
+	merged := map[string]string{}
+	for key, value := range e.current {
+		if !e.delVars[key] {
+			merged[key] = value
+		}
+	}
+	for key, value := range e.addVars {
+		merged[key] = value
+	}
+	output := make([]string, 0, len(merged))
+	for _, key := range sortedKeys(merged) {
+		output = append(output, key+"="+merged[key])
+	}
+	return output
+}
+
+// WriteStatements writes a list of export and unset statements to update the environment of a bash or zsh shell. The
+// values are quoted with QuotePosix, so they are safe to eval even if they contain shell metacharacters.
 func (e *Environment) WriteStatements() string {
+	return e.WriteStatementsFor(dialects["bash"])
+}
+
+// WriteStatementsFor writes the statements to update the environment in the syntax of the given shell dialect. The
+// statements are sorted by variable name to get a stable output.
+func (e *Environment) WriteStatementsFor(dialect ShellDialect) string {
 	var output []string
-	for key, value := range e.addVars {
-		output = append(output, fmt.Sprintf("export %s=\"%s\"", key, value))
+	for _, key := range sortedKeys(e.addVars) {
+		output = append(output, dialect.SetStatement(key, e.addVars[key]))
 	}
-	for key := range e.delVars {
-		output = append(output, fmt.Sprintf("unset %s", key))
+	for _, key := range sortedKeys(e.delVars) {
+		output = append(output, dialect.UnsetStatement(key))
 	}
 	return strings.Join(output, ";")
 }
+
+// sortedKeys returns the keys of the map in ascending order
+func sortedKeys[V any](in map[string]V) []string {
+	keys := make([]string, 0, len(in))
+	for key := range in {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -86,6 +86,15 @@ func TestEnvironment_Set(t *testing.T) {
 			want:    map[string]string{},
 			wantErr: true,
 		},
+		{
+			name: "Set invalid key",
+			args: args{
+				key:   "EDITOR;rm -rf ~",
+				value: "vi",
+			},
+			want:    map[string]string{},
+			wantErr: true,
+		},
 		{
 			name: "Set empty key and value",
 			args: args{
@@ -230,7 +239,7 @@ func TestEnvironment_WriteStatements(t *testing.T) {
 	_ = e.Set("FOO_PATH", "/tmp/foo")
 	_ = e.Set("BAR_PATH", "/tmp/bar")
 	_ = e.Unset("EDITOR")
-	want := "export FOO_PATH=\"/tmp/foo\";export BAR_PATH=\"/tmp/bar\";unset EDITOR"
+	want := "export BAR_PATH='/tmp/bar';export FOO_PATH='/tmp/foo';unset EDITOR"
 	got := e.WriteStatements()
 	if !reflect.DeepEqual(want, got) {
 		t.Errorf("WriteStatements() = %v, want %v", got, want)
@@ -248,6 +257,9 @@ func TestNewEnvironment(t *testing.T) {
 	if e.current == nil {
 		t.Errorf("current was not initialized")
 	}
+	if e.layers == nil {
+		t.Errorf("layers was not initialized")
+	}
 }
 
 func prepareEnv(clearCurrent bool, newVars map[string]string) error {
@@ -319,3 +331,43 @@ func TestEnvironment_GetCurrent(t *testing.T) {
 		})
 	}
 }
+
+func TestEnvironment_Lookup(t *testing.T) {
+	e := Environment{
+		current: map[string]string{"EDITOR": "vi", "PAGER": "less", "SHELL": "/bin/bash"},
+		addVars: map[string]string{"EDITOR": "vim", "GOPATH": "/tmp/go"},
+		delVars: map[string]bool{"PAGER": true},
+	}
+	tests := []struct {
+		name       string
+		key        string
+		want       string
+		wantExists bool
+	}{
+		{name: "Current variable", key: "SHELL", want: "/bin/bash", wantExists: true},
+		{name: "Overwritten variable", key: "EDITOR", want: "vim", wantExists: true},
+		{name: "Added variable", key: "GOPATH", want: "/tmp/go", wantExists: true},
+		{name: "Removed variable", key: "PAGER", want: "", wantExists: false},
+		{name: "Unknown variable", key: "HOME", want: "", wantExists: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotExists := e.Lookup(tt.key)
+			if got != tt.want || gotExists != tt.wantExists {
+				t.Errorf("Lookup() = (%v, %v), want (%v, %v)", got, gotExists, tt.want, tt.wantExists)
+			}
+		})
+	}
+}
+
+func TestEnvironment_Environ(t *testing.T) {
+	e := Environment{
+		current: map[string]string{"EDITOR": "vi", "PAGER": "less", "SHELL": "/bin/bash"},
+		addVars: map[string]string{"EDITOR": "vim", "GOPATH": "/tmp/go", "MULTI": "a=b\nc"},
+		delVars: map[string]bool{"PAGER": true},
+	}
+	want := []string{"EDITOR=vim", "GOPATH=/tmp/go", "MULTI=a=b\nc", "SHELL=/bin/bash"}
+	if got := e.Environ(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Environ() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,156 @@
+package environment
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+)
+
+// PreviousValuesVariableName is the name of the variable which records the values overwritten by Push, so Pop can
+// restore them later.
+const PreviousValuesVariableName = "ENVMANAGER_PREVIOUS"
+
+// layer records that a variable was set by owner and which value it had before
+type layer struct {
+	//Owner is the name of whoever set the variable, e.g. a profile
+	Owner string `json:"owner"`
+	//Previous is the value before the owner set the variable, nil if the variable was not set
+	Previous *string `json:"previous,omitempty"`
+}
+
+// Push sets the variable like Set but records the value it had before (or its absence), so Pop can restore it. The
+// records are stacked, every owner setting the same variable adds a layer. If the owner already set the variable, its
+// layer moves to the top of the stack.
+func (e *Environment) Push(owner string, key string, value string) error {
+	if err := ValidateVariableName(key); err != nil {
+		return err
+	}
+	layers := e.layers[key]
+	index := findLayer(layers, owner)
+	// if the owner was the last one setting the variable, its layer already knows the value before
+	if index == -1 || index != len(layers)-1 {
+		if index != -1 {
+			layers = removeLayer(layers, index)
+		}
+		var previous *string
+		if current, exists := e.Lookup(key); exists {
+			previous = &current
+		}
+		layers = append(layers, layer{Owner: owner, Previous: previous})
+	}
+	if err := e.storeLayers(key, layers); err != nil {
+		return err
+	}
+	return e.Set(key, value)
+}
+
+// Pop reverts the Push of owner. If the owner was the last one setting the variable, the value before its Push is
+// restored (or the variable removed). Otherwise, the variable keeps its value, and the layer set after the owner's one
+// inherits the value to restore. A variable without any recorded layers is removed, like Unset does.
+func (e *Environment) Pop(owner string, key string) error {
+	if err := ValidateVariableName(key); err != nil {
+		return err
+	}
+	layers := e.layers[key]
+	if len(layers) == 0 {
+		// nothing recorded, e.g. loaded by a version without this feature
+		return e.Unset(key)
+	}
+	index := findLayer(layers, owner)
+	if index == -1 {
+		// the variable is owned by others, it is not ours to change
+		return nil
+	}
+	previous := layers[index].Previous
+	isTopLayer := index == len(layers)-1
+	if err := e.storeLayers(key, removeLayer(layers, index)); err != nil {
+		return err
+	}
+	if !isTopLayer {
+		return nil
+	}
+	if previous == nil {
+		return e.Unset(key)
+	}
+	return e.Set(key, *previous)
+}
+
+// PopOwner calls Pop for every variable owner has pushed and RemoveFromList for every list owner added items to. This
+// reverts the changes of an owner without knowing which variables it set.
+func (e *Environment) PopOwner(owner string) error {
+	for _, key := range sortedKeys(e.listChanges) {
+		if err := e.RemoveFromList(owner, key); err != nil {
+			return err
+		}
+	}
+	for _, key := range sortedKeys(e.layers) {
+		if findLayer(e.layers[key], owner) == -1 {
+			continue
+		}
+		if err := e.Pop(owner, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadLayers reads the recorded layers from the variable PreviousValuesVariableName of the current environment. An
+// unreadable record is ignored, as there is no way to recover from it.
+func (e *Environment) loadLayers() {
+	e.layers = map[string][]layer{}
+	encoded, exists := e.current[PreviousValuesVariableName]
+	if !exists {
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return
+	}
+	var layers map[string][]layer
+	if json.Unmarshal(data, &layers) == nil && layers != nil {
+		e.layers = layers
+	}
+}
+
+// storeLayers replaces the layers of key and writes all layers to the variable PreviousValuesVariableName. The
+// variable is removed if there are no layers left.
+func (e *Environment) storeLayers(key string, layers []layer) error {
+	if e.layers == nil {
+		e.layers = map[string][]layer{}
+	}
+	if len(layers) == 0 {
+		delete(e.layers, key)
+	} else {
+		e.layers[key] = layers
+	}
+
+	if len(e.layers) == 0 {
+		if _, exists := e.current[PreviousValuesVariableName]; exists {
+			return e.Unset(PreviousValuesVariableName)
+		}
+		delete(e.addVars, PreviousValuesVariableName)
+		return nil
+	}
+	data, err := json.Marshal(e.layers)
+	if err != nil {
+		return err
+	}
+	return e.Set(PreviousValuesVariableName, base64.StdEncoding.EncodeToString(data))
+}
+
+// findLayer returns the index of the layer set by owner or -1 if there is none
+func findLayer(layers []layer, owner string) int {
+	return slices.IndexFunc(layers, func(l layer) bool {
+		return l.Owner == owner
+	})
+}
+
+// removeLayer removes the layer at index. The layer above it inherits the value to restore, as the removed layer's
+// value is gone.
+func removeLayer(layers []layer, index int) []layer {
+	layers = slices.Clone(layers)
+	if index < len(layers)-1 {
+		layers[index+1].Previous = layers[index].Previous
+	}
+	return slices.Delete(layers, index, index+1)
+}
@@ -0,0 +1,182 @@
+package environment
+
+import (
+	"strings"
+	"testing"
+)
+
+// nextInvocation simulates a new envManager call in a shell which applied the statements of e
+func nextInvocation(t *testing.T, e Environment) Environment {
+	t.Helper()
+	next := NewEnvironment()
+	for _, variable := range e.Environ() {
+		key, value, _ := strings.Cut(variable, "=")
+		next.current[key] = value
+	}
+	next.loadLayers()
+	next.loadListChanges()
+	return next
+}
+
+// assertVariable checks the value a variable will have after applying the statements
+func assertVariable(t *testing.T, e Environment, key string, want string, wantExists bool) {
+	t.Helper()
+	got, gotExists := e.Lookup(key)
+	if got != want || gotExists != wantExists {
+		t.Errorf("%s = (%q, %v), want (%q, %v)", key, got, gotExists, want, wantExists)
+	}
+}
+
+func TestEnvironment_PushPop(t *testing.T) {
+	t.Run("Restore overwritten variable", func(t *testing.T) {
+		e := NewEnvironment()
+		e.current["AWS_REGION"] = "eu-central-1"
+		_ = e.Push("aws", "AWS_REGION", "us-east-1")
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "AWS_REGION", "us-east-1", true)
+
+		_ = e.Pop("aws", "AWS_REGION")
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "AWS_REGION", "eu-central-1", true)
+		assertVariable(t, e, PreviousValuesVariableName, "", false)
+	})
+
+	t.Run("Remove variable which was not set before", func(t *testing.T) {
+		e := NewEnvironment()
+		_ = e.Push("aws", "AWS_REGION", "us-east-1")
+		e = nextInvocation(t, e)
+		_ = e.Pop("aws", "AWS_REGION")
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "AWS_REGION", "", false)
+		assertVariable(t, e, PreviousValuesVariableName, "", false)
+	})
+
+	t.Run("Restore empty value", func(t *testing.T) {
+		e := NewEnvironment()
+		e.current["AWS_REGION"] = ""
+		_ = e.Push("aws", "AWS_REGION", "us-east-1")
+		_ = e.Pop("aws", "AWS_REGION")
+		assertVariable(t, e, "AWS_REGION", "", true)
+	})
+
+	t.Run("Stacked loads, unload in reverse order", func(t *testing.T) {
+		e := NewEnvironment()
+		e.current["AWS_REGION"] = "original"
+		_ = e.Push("first", "AWS_REGION", "first-value")
+		e = nextInvocation(t, e)
+		_ = e.Push("second", "AWS_REGION", "second-value")
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "AWS_REGION", "second-value", true)
+
+		_ = e.Pop("second", "AWS_REGION")
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "AWS_REGION", "first-value", true)
+		_ = e.Pop("first", "AWS_REGION")
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "AWS_REGION", "original", true)
+	})
+
+	t.Run("Stacked loads, unload in load order", func(t *testing.T) {
+		e := NewEnvironment()
+		e.current["AWS_REGION"] = "original"
+		_ = e.Push("first", "AWS_REGION", "first-value")
+		e = nextInvocation(t, e)
+		_ = e.Push("second", "AWS_REGION", "second-value")
+		e = nextInvocation(t, e)
+
+		// the second profile still is loaded, so its value stays
+		_ = e.Pop("first", "AWS_REGION")
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "AWS_REGION", "second-value", true)
+		// the second profile now restores the value from before the first one was loaded
+		_ = e.Pop("second", "AWS_REGION")
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "AWS_REGION", "original", true)
+		assertVariable(t, e, PreviousValuesVariableName, "", false)
+	})
+
+	t.Run("Stacked loads in one invocation", func(t *testing.T) {
+		e := NewEnvironment()
+		_ = e.Push("first", "AWS_REGION", "first-value")
+		_ = e.Push("second", "AWS_REGION", "second-value")
+		e = nextInvocation(t, e)
+		_ = e.Pop("second", "AWS_REGION")
+		assertVariable(t, e, "AWS_REGION", "first-value", true)
+		_ = e.Pop("first", "AWS_REGION")
+		assertVariable(t, e, "AWS_REGION", "", false)
+	})
+
+	t.Run("Loading the same owner twice keeps the original value", func(t *testing.T) {
+		e := NewEnvironment()
+		e.current["AWS_REGION"] = "original"
+		_ = e.Push("aws", "AWS_REGION", "us-east-1")
+		e = nextInvocation(t, e)
+		_ = e.Push("aws", "AWS_REGION", "us-east-2")
+		e = nextInvocation(t, e)
+		_ = e.Pop("aws", "AWS_REGION")
+		assertVariable(t, e, "AWS_REGION", "original", true)
+	})
+
+	t.Run("Reloading an owner below another one moves it to the top", func(t *testing.T) {
+		e := NewEnvironment()
+		e.current["AWS_REGION"] = "original"
+		_ = e.Push("first", "AWS_REGION", "first-value")
+		_ = e.Push("second", "AWS_REGION", "second-value")
+		_ = e.Push("first", "AWS_REGION", "first-value")
+		e = nextInvocation(t, e)
+
+		_ = e.Pop("first", "AWS_REGION")
+		assertVariable(t, e, "AWS_REGION", "second-value", true)
+		_ = e.Pop("second", "AWS_REGION")
+		assertVariable(t, e, "AWS_REGION", "original", true)
+	})
+
+	t.Run("Pop of an owner which did not set the variable", func(t *testing.T) {
+		e := NewEnvironment()
+		_ = e.Push("first", "AWS_REGION", "first-value")
+		_ = e.Pop("other", "AWS_REGION")
+		assertVariable(t, e, "AWS_REGION", "first-value", true)
+	})
+
+	t.Run("Pop without recorded layers removes the variable", func(t *testing.T) {
+		e := NewEnvironment()
+		e.current["AWS_REGION"] = "loaded-by-old-version"
+		_ = e.Pop("aws", "AWS_REGION")
+		assertVariable(t, e, "AWS_REGION", "", false)
+	})
+
+	t.Run("Unreadable record is ignored", func(t *testing.T) {
+		e := NewEnvironment()
+		e.current[PreviousValuesVariableName] = "not base64!"
+		e.current["AWS_REGION"] = "us-east-1"
+		e.loadLayers()
+		_ = e.Pop("aws", "AWS_REGION")
+		assertVariable(t, e, "AWS_REGION", "", false)
+	})
+
+	t.Run("Invalid keys", func(t *testing.T) {
+		e := NewEnvironment()
+		if err := e.Push("aws", "", "value"); err == nil {
+			t.Error("Push() with empty key got no error but wanted one")
+		}
+		if err := e.Pop("aws", "INVALID-KEY"); err == nil {
+			t.Error("Pop() with invalid key got no error but wanted one")
+		}
+	})
+}
+
+func TestEnvironment_PopOwner(t *testing.T) {
+	e := NewEnvironment()
+	e.current["AWS_REGION"] = "original"
+	_ = e.Push("aws", "AWS_REGION", "us-east-1")
+	_ = e.Push("aws", "AWS_PROFILE", "admin")
+	_ = e.Push("db", "DB_USER", "admin")
+	e = nextInvocation(t, e)
+
+	if err := e.PopOwner("aws"); err != nil {
+		t.Fatalf("PopOwner() returned error %v", err)
+	}
+	assertVariable(t, e, "AWS_REGION", "original", true)
+	assertVariable(t, e, "AWS_PROFILE", "", false)
+	assertVariable(t, e, "DB_USER", "admin", true)
+}
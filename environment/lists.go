@@ -0,0 +1,157 @@
+package environment
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// ListsVariableName is the name of the variable which records the items added to list variables like PATH, so
+// RemoveFromList can remove exactly these items later.
+const ListsVariableName = "ENVMANAGER_LISTS"
+
+// listChange records the items owner added to a list variable
+type listChange struct {
+	//Owner is the name of whoever added the items, e.g. a profile
+	Owner     string `json:"owner"`
+	Separator string `json:"separator"`
+	//Prepended are the items added to the front of the list
+	Prepended []string `json:"prepended,omitempty"`
+	//Appended are the items added to the end of the list
+	Appended []string `json:"appended,omitempty"`
+	//Created is true if the variable was not set before the items were added
+	Created bool `json:"created,omitempty"`
+}
+
+// AddToList adds items to the front (prependItems) and the end (appendItems) of the list variable key, whose items are
+// separated by separator, e.g. PATH. The current value of the variable is kept. The added items are recorded, so
+// RemoveFromList can remove them even if the variable was changed in between. If owner already added items to the
+// variable, they are removed first.
+func (e *Environment) AddToList(owner, key, separator string, prependItems, appendItems []string) error {
+	if err := ValidateVariableName(key); err != nil {
+		return err
+	}
+	if separator == "" {
+		return errors.New("the separator of a list must not be empty")
+	}
+	if err := e.RemoveFromList(owner, key); err != nil {
+		return err
+	}
+	current, exists := e.Lookup(key)
+	items := slices.Concat(prependItems, splitList(current, separator), appendItems)
+	changes := append(slices.Clone(e.listChanges[key]), listChange{
+		Owner:     owner,
+		Separator: separator,
+		Prepended: prependItems,
+		Appended:  appendItems,
+		Created:   !exists,
+	})
+	if err := e.storeListChanges(key, changes); err != nil {
+		return err
+	}
+	return e.Set(key, strings.Join(items, separator))
+}
+
+// RemoveFromList removes the items owner added to the list variable key with AddToList. Items added by others or by
+// the user are kept. The variable is removed if it did not exist before and no items are left.
+func (e *Environment) RemoveFromList(owner string, key string) error {
+	if err := ValidateVariableName(key); err != nil {
+		return err
+	}
+	changes := e.listChanges[key]
+	index := slices.IndexFunc(changes, func(change listChange) bool {
+		return change.Owner == owner
+	})
+	if index == -1 {
+		return nil
+	}
+	change := changes[index]
+	if err := e.storeListChanges(key, slices.Delete(slices.Clone(changes), index, index+1)); err != nil {
+		return err
+	}
+
+	current, exists := e.Lookup(key)
+	if !exists {
+		// the variable was removed by someone else, there is nothing left to remove
+		return nil
+	}
+	items := splitList(current, change.Separator)
+	for _, item := range change.Prepended {
+		if i := slices.Index(items, item); i != -1 {
+			items = slices.Delete(items, i, i+1)
+		}
+	}
+	for _, item := range change.Appended {
+		if i := lastIndex(items, item); i != -1 {
+			items = slices.Delete(items, i, i+1)
+		}
+	}
+	if len(items) == 0 && change.Created {
+		return e.Unset(key)
+	}
+	return e.Set(key, strings.Join(items, change.Separator))
+}
+
+// splitList splits the value of a list variable into its items. An empty value has no items.
+func splitList(value string, separator string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, separator)
+}
+
+// lastIndex returns the index of the last occurrence of item in items or -1 if there is none
+func lastIndex(items []string, item string) int {
+	for i := len(items) - 1; i >= 0; i-- {
+		if items[i] == item {
+			return i
+		}
+	}
+	return -1
+}
+
+// loadListChanges reads the recorded list changes from the variable ListsVariableName of the current environment. An
+// unreadable record is ignored, as there is no way to recover from it.
+func (e *Environment) loadListChanges() {
+	e.listChanges = map[string][]listChange{}
+	encoded, exists := e.current[ListsVariableName]
+	if !exists {
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return
+	}
+	var changes map[string][]listChange
+	if json.Unmarshal(data, &changes) == nil && changes != nil {
+		e.listChanges = changes
+	}
+}
+
+// storeListChanges replaces the list changes of key and writes all changes to the variable ListsVariableName. The
+// variable is removed if there are no changes left.
+func (e *Environment) storeListChanges(key string, changes []listChange) error {
+	if e.listChanges == nil {
+		e.listChanges = map[string][]listChange{}
+	}
+	if len(changes) == 0 {
+		delete(e.listChanges, key)
+	} else {
+		e.listChanges[key] = changes
+	}
+
+	if len(e.listChanges) == 0 {
+		if _, exists := e.current[ListsVariableName]; exists {
+			return e.Unset(ListsVariableName)
+		}
+		delete(e.addVars, ListsVariableName)
+		return nil
+	}
+	data, err := json.Marshal(e.listChanges)
+	if err != nil {
+		return err
+	}
+	return e.Set(ListsVariableName, base64.StdEncoding.EncodeToString(data))
+}
@@ -0,0 +1,122 @@
+package environment
+
+import "testing"
+
+func TestEnvironment_AddToList(t *testing.T) {
+	t.Run("Prepend and append to the current value", func(t *testing.T) {
+		e := NewEnvironment()
+		e.current["PATH"] = "/usr/bin:/bin"
+		_ = e.AddToList("go", "PATH", ":", []string{"/opt/go/bin"}, []string{"/home/user/go/bin"})
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "PATH", "/opt/go/bin:/usr/bin:/bin:/home/user/go/bin", true)
+
+		_ = e.RemoveFromList("go", "PATH")
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "PATH", "/usr/bin:/bin", true)
+		assertVariable(t, e, ListsVariableName, "", false)
+	})
+
+	t.Run("Keep items added in between", func(t *testing.T) {
+		e := NewEnvironment()
+		e.current["PATH"] = "/usr/bin"
+		_ = e.AddToList("go", "PATH", ":", []string{"/opt/go/bin"}, nil)
+		e = nextInvocation(t, e)
+		// the user changes PATH after loading the profile
+		_ = e.Set("PATH", "/home/user/bin:"+e.GetCurrent("PATH", ""))
+		e = nextInvocation(t, e)
+
+		_ = e.RemoveFromList("go", "PATH")
+		assertVariable(t, e, "PATH", "/home/user/bin:/usr/bin", true)
+	})
+
+	t.Run("Keep duplicates which were present before", func(t *testing.T) {
+		e := NewEnvironment()
+		e.current["PATH"] = "/usr/local/bin:/usr/bin:/usr/local/bin"
+		_ = e.AddToList("local", "PATH", ":", []string{"/usr/local/bin"}, []string{"/usr/local/bin"})
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "PATH", "/usr/local/bin:/usr/local/bin:/usr/bin:/usr/local/bin:/usr/local/bin", true)
+
+		_ = e.RemoveFromList("local", "PATH")
+		assertVariable(t, e, "PATH", "/usr/local/bin:/usr/bin:/usr/local/bin", true)
+	})
+
+	t.Run("Remove variable which was not set before", func(t *testing.T) {
+		e := NewEnvironment()
+		_ = e.AddToList("py", "PYTHONPATH", ":", nil, []string{"/opt/lib"})
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "PYTHONPATH", "/opt/lib", true)
+
+		_ = e.RemoveFromList("py", "PYTHONPATH")
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "PYTHONPATH", "", false)
+	})
+
+	t.Run("Keep empty variable which was set before", func(t *testing.T) {
+		e := NewEnvironment()
+		e.current["PYTHONPATH"] = ""
+		_ = e.AddToList("py", "PYTHONPATH", ":", nil, []string{"/opt/lib"})
+		_ = e.RemoveFromList("py", "PYTHONPATH")
+		assertVariable(t, e, "PYTHONPATH", "", true)
+	})
+
+	t.Run("Stacked owners, unload in load order", func(t *testing.T) {
+		e := NewEnvironment()
+		e.current["PATH"] = "/usr/bin"
+		_ = e.AddToList("first", "PATH", ":", []string{"/first"}, nil)
+		e = nextInvocation(t, e)
+		_ = e.AddToList("second", "PATH", ":", []string{"/second"}, []string{"/second-end"})
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "PATH", "/second:/first:/usr/bin:/second-end", true)
+
+		_ = e.RemoveFromList("first", "PATH")
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "PATH", "/second:/usr/bin:/second-end", true)
+		_ = e.RemoveFromList("second", "PATH")
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "PATH", "/usr/bin", true)
+		assertVariable(t, e, ListsVariableName, "", false)
+	})
+
+	t.Run("Adding again replaces the items of the owner", func(t *testing.T) {
+		e := NewEnvironment()
+		e.current["PATH"] = "/usr/bin"
+		_ = e.AddToList("go", "PATH", ":", []string{"/old"}, nil)
+		e = nextInvocation(t, e)
+		_ = e.AddToList("go", "PATH", ":", []string{"/new"}, nil)
+		e = nextInvocation(t, e)
+		assertVariable(t, e, "PATH", "/new:/usr/bin", true)
+	})
+
+	t.Run("Custom separator", func(t *testing.T) {
+		e := NewEnvironment()
+		e.current["FLAGS"] = "-a,-b"
+		_ = e.AddToList("flags", "FLAGS", ",", []string{"-c"}, nil)
+		assertVariable(t, e, "FLAGS", "-c,-a,-b", true)
+	})
+
+	t.Run("Invalid arguments", func(t *testing.T) {
+		e := NewEnvironment()
+		if err := e.AddToList("x", "PATH", "", nil, nil); err == nil {
+			t.Error("AddToList() with an empty separator returned no error")
+		}
+		if err := e.AddToList("x", "1PATH", ":", nil, nil); err == nil {
+			t.Error("AddToList() with an invalid name returned no error")
+		}
+	})
+}
+
+func TestEnvironment_PopOwner_lists(t *testing.T) {
+	e := NewEnvironment()
+	e.current["PATH"] = "/usr/bin"
+	_ = e.Push("go", "GOPATH", "/home/user/go")
+	_ = e.AddToList("go", "PATH", ":", []string{"/opt/go/bin"}, nil)
+	_ = e.AddToList("other", "PATH", ":", []string{"/other"}, nil)
+	e = nextInvocation(t, e)
+
+	if err := e.PopOwner("go"); err != nil {
+		t.Fatalf("PopOwner() returned error %v", err)
+	}
+	e = nextInvocation(t, e)
+	assertVariable(t, e, "PATH", "/other:/usr/bin", true)
+	assertVariable(t, e, "GOPATH", "", false)
+}
@@ -0,0 +1,36 @@
+package environment
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// variableNamePattern matches the names a POSIX shell accepts for environment variables
+var variableNamePattern = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+
+// ValidateVariableName checks that name can be used as environment variable name in a shell statement. Since the name
+// is written into the statements unquoted, anything else would allow injecting shell code.
+func ValidateVariableName(name string) error {
+	if name == "" {
+		return errors.New("key must not be empty")
+	}
+	if !variableNamePattern.MatchString(name) {
+		return fmt.Errorf("%q is not a valid variable name, only letters, digits and underscores are allowed and it must not start with a digit", name)
+	}
+	return nil
+}
+
+// QuotePosix wraps value in single quotes so a POSIX shell takes it literally. As there is no way to escape a single
+// quote within single quotes, each one in the value ends the quoted string, adds an escaped quote and starts a new
+// quoted string.
+func QuotePosix(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// QuoteFish wraps value in single quotes so the fish shell takes it literally. Within single quotes, fish only
+// interprets escaped backslashes and escaped single quotes.
+func QuoteFish(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
@@ -0,0 +1,115 @@
+package environment
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestValidateVariableName(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{name: "Upper case", key: "FOO_PATH", wantErr: false},
+		{name: "Lower case", key: "foo_path", wantErr: false},
+		{name: "Leading underscore", key: "_FOO", wantErr: false},
+		{name: "Digits", key: "FOO2", wantErr: false},
+		{name: "Empty", key: "", wantErr: true},
+		{name: "Leading digit", key: "2FOO", wantErr: true},
+		{name: "Dash", key: "FOO-PATH", wantErr: true},
+		{name: "Space", key: "FOO PATH", wantErr: true},
+		{name: "Command substitution", key: "FOO$(id)", wantErr: true},
+		{name: "Statement separator", key: "FOO;id", wantErr: true},
+		{name: "Unicode", key: "FÖÖ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateVariableName(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateVariableName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQuotePosix(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "Empty", value: "", want: "''"},
+		{name: "Plain", value: "secret", want: "'secret'"},
+		{name: "Single quote", value: "it's", want: `'it'\''s'`},
+		{name: "Double quote", value: `say "hi"`, want: `'say "hi"'`},
+		{name: "Dollar and backtick", value: "$HOME`id`", want: "'$HOME`id`'"},
+		{name: "Backslash", value: `C:\temp`, want: `'C:\temp'`},
+		{name: "Newline", value: "line1\nline2", want: "'line1\nline2'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := QuotePosix(tt.value); got != tt.want {
+				t.Errorf("QuotePosix() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEnvironment_WriteStatements_evalRoundTrip evaluates the generated statements in a real shell and checks that the
+// values arrive unchanged.
+func TestEnvironment_WriteStatements_evalRoundTrip(t *testing.T) {
+	shell, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh available to evaluate the statements")
+	}
+	values := map[string]string{
+		"empty":                "",
+		"double quotes":        `pa"ss"word`,
+		"single quotes":        `pa'ss'word`,
+		"dollar":               "pa$word$(id)${HOME}",
+		"backticks":            "pa`id`ss",
+		"backslashes":          `pa\ss\\word\`,
+		"newlines":             "line1\nline2\n",
+		"unicode":              "pässwörd 🔑 密码",
+		"shell metacharacters": "a;b&c|d<e>f(g)h{i}j*k?l[m]n~o#p!q",
+		"all of them":          "'\"$`\\\n;&|*!",
+	}
+	for name, value := range values {
+		t.Run(name, func(t *testing.T) {
+			e := NewEnvironment()
+			if err := e.Set("SECRET", value); err != nil {
+				t.Fatalf("Set() returned error %v", err)
+			}
+			script := e.WriteStatements() + ";printf '%s' \"$SECRET\""
+			got, err := exec.Command(shell, "-c", script).Output()
+			if err != nil {
+				t.Fatalf("Evaluating %q failed with %v", script, err)
+			}
+			if string(got) != value {
+				t.Errorf("Shell got %q, want %q", string(got), value)
+			}
+		})
+	}
+}
+
+func TestQuoteFish(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "Empty", value: "", want: "''"},
+		{name: "Plain", value: "secret", want: "'secret'"},
+		{name: "Single quote", value: "it's", want: `'it\'s'`},
+		{name: "Backslash", value: `C:\temp`, want: `'C:\\temp'`},
+		{name: "Dollar and parentheses", value: "$HOME(id)", want: "'$HOME(id)'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := QuoteFish(tt.value); got != tt.want {
+				t.Errorf("QuoteFish() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package environment
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// SessionDirVariableName is the name of the variable holding the directory of the files written for the shell session
+const SessionDirVariableName = "ENVMANAGER_SESSION_DIR"
+
+// sessionBaseDirs returns the directories which may hold the session directories, the preferred one first. They are
+// usually backed by a tmpfs, so the files never reach the disk.
+func sessionBaseDirs() []string {
+	var dirs []string
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		dirs = append(dirs, filepath.Join(runtimeDir, "envManager"))
+	}
+	uid := strconv.Itoa(os.Getuid())
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		dirs = append(dirs, filepath.Join("/dev/shm", "envManager-"+uid))
+	}
+	return append(dirs, filepath.Join(os.TempDir(), "envManager-"+uid))
+}
+
+// getSessionBaseDir returns the directory holding the session directories and creates it if needed
+func getSessionBaseDir() (string, error) {
+	var errs []error
+	for _, dir := range sessionBaseDirs() {
+		if err := ensurePrivateDir(dir); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return dir, nil
+	}
+	return "", fmt.Errorf("failed to create a private directory for the session files: %w", errors.Join(errs...))
+}
+
+// ensurePrivateDir creates dir if it does not exist and checks that only the current user can access it
+func ensurePrivateDir(dir string) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+		return err
+	}
+	if err := os.Mkdir(dir, 0700); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s can be accessed by other users", dir)
+	}
+	if stat, isStat := info.Sys().(*syscall.Stat_t); isStat && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is owned by another user", dir)
+	}
+	return nil
+}
+
+// CreateSessionDir creates the directory for the files of the session of the process with the pid. The directory is
+// removed by CleanStaleSessionDirs once the process is gone.
+func CreateSessionDir(pid int) (string, error) {
+	baseDir, err := getSessionBaseDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(baseDir, strconv.Itoa(pid))
+	if err := ensurePrivateDir(dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// SetSessionOwner sets the process owning the session directory, e.g. envManager itself if it starts a subshell. By
+// default, the shell (the parent process) owns it. The session directory of the shell is not used then.
+func (e *Environment) SetSessionOwner(pid int) {
+	e.sessionOwner = pid
+}
+
+// GetSessionDir returns the directory for the files of the session. The directory recorded in SessionDirVariableName
+// is used if it still exists and belongs to the owner of the session. Otherwise, e.g. in a tmux pane or nested shell
+// inheriting the variable, a directory for the owner is created and recorded in the variable.
+func (e *Environment) GetSessionDir() (string, error) {
+	owner := e.sessionOwner
+	if owner == 0 {
+		owner = os.Getppid()
+	}
+	if dir, isSet := e.Lookup(SessionDirVariableName); isSet && filepath.Base(dir) == strconv.Itoa(owner) {
+		if info, err := os.Lstat(dir); err == nil && info.IsDir() {
+			return dir, nil
+		}
+	}
+	dir, err := CreateSessionDir(owner)
+	if err != nil {
+		return "", err
+	}
+	return dir, e.Set(SessionDirVariableName, dir)
+}
+
+// RemoveSessionDir removes the session directory of the process with the pid, if it exists
+func RemoveSessionDir(pid int) error {
+	var errs []error
+	for _, baseDir := range sessionBaseDirs() {
+		if err := os.RemoveAll(filepath.Join(baseDir, strconv.Itoa(pid))); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CleanStaleSessionDirs removes the session directories of processes which are no longer running, e.g. of closed
+// shells
+func CleanStaleSessionDirs() error {
+	var errs []error
+	for _, baseDir := range sessionBaseDirs() {
+		entries, err := os.ReadDir(baseDir)
+		if err != nil {
+			// the directory is only created when it is needed
+			continue
+		}
+		for _, entry := range entries {
+			pid, err := strconv.Atoi(entry.Name())
+			if err != nil || !entry.IsDir() || isProcessRunning(pid) {
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(baseDir, entry.Name())); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isProcessRunning checks if a process with the pid exists
+func isProcessRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	// EPERM means the process exists but belongs to another user
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,135 @@
+package environment
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestEnvironment_GetSessionDir(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	wantDir := filepath.Join(runtimeDir, "envManager", strconv.Itoa(os.Getppid()))
+
+	e := NewEnvironment()
+	dir, err := e.GetSessionDir()
+	if err != nil {
+		t.Fatalf("GetSessionDir() returned error %v", err)
+	}
+	if dir != wantDir {
+		t.Errorf("GetSessionDir() = %s, want %s", dir, wantDir)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatalf("GetSessionDir() did not create the directory: %v", err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("GetSessionDir() created the directory with mode %o, want 700", info.Mode().Perm())
+	}
+	assertVariable(t, e, SessionDirVariableName, dir, true)
+
+	// the next invocation in the same shell uses the recorded directory
+	next := nextInvocation(t, e)
+	if nextDir, _ := next.GetSessionDir(); nextDir != dir {
+		t.Errorf("GetSessionDir() of the next invocation = %s, want %s", nextDir, dir)
+	}
+
+	// a subshell started by envManager gets its own directory
+	next.SetSessionOwner(os.Getpid())
+	ownDir, err := next.GetSessionDir()
+	if err != nil {
+		t.Fatalf("GetSessionDir() returned error %v", err)
+	}
+	if ownDir != filepath.Join(runtimeDir, "envManager", strconv.Itoa(os.Getpid())) {
+		t.Errorf("GetSessionDir() with owner = %s, want the directory of the owner", ownDir)
+	}
+	if err := RemoveSessionDir(os.Getpid()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(ownDir); !os.IsNotExist(err) {
+		t.Errorf("RemoveSessionDir() did not remove %s", ownDir)
+	}
+}
+
+func TestEnvironment_GetSessionDir_inheritedDirectory(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	// e.g. a tmux pane inherits the directory of the shell which started the tmux server
+	otherDir, err := CreateSessionDir(os.Getppid() + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewEnvironment()
+	_ = e.Set(SessionDirVariableName, otherDir)
+	next := nextInvocation(t, e)
+	dir, err := next.GetSessionDir()
+	if err != nil {
+		t.Fatalf("GetSessionDir() returned error %v", err)
+	}
+	if wantDir := filepath.Join(runtimeDir, "envManager", strconv.Itoa(os.Getppid())); dir != wantDir {
+		t.Errorf("GetSessionDir() = %s, want %s instead of the directory of another process", dir, wantDir)
+	}
+	assertVariable(t, next, SessionDirVariableName, dir, true)
+}
+
+func TestEnvironment_GetSessionDir_sharedDirectory(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	// a directory other users can access must not be used
+	if err := os.Mkdir(filepath.Join(runtimeDir, "envManager"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(runtimeDir, "envManager"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewEnvironment()
+	dir, err := e.GetSessionDir()
+	if err != nil {
+		t.Fatalf("GetSessionDir() returned error %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	if filepath.Dir(dir) == filepath.Join(runtimeDir, "envManager") {
+		t.Errorf("GetSessionDir() = %s, want a directory outside the shared directory", dir)
+	}
+}
+
+func TestCleanStaleSessionDirs(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+	// the pid of a process which already exited
+	finished := exec.Command("true")
+	if err := finished.Run(); err != nil {
+		t.Skipf("cannot run a process: %v", err)
+	}
+	staleDir, err := CreateSessionDir(finished.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(staleDir, "secret"), []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	liveDir, err := CreateSessionDir(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = RemoveSessionDir(os.Getpid())
+	})
+
+	if err := CleanStaleSessionDirs(); err != nil {
+		t.Fatalf("CleanStaleSessionDirs() returned error %v", err)
+	}
+	if _, err := os.Stat(staleDir); !os.IsNotExist(err) {
+		t.Errorf("CleanStaleSessionDirs() did not remove %s", staleDir)
+	}
+	if _, err := os.Stat(liveDir); err != nil {
+		t.Errorf("CleanStaleSessionDirs() removed the directory of a running process: %v", err)
+	}
+}
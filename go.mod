@@ -1,21 +1,23 @@
 module envManager
 
-go 1.23
+go 1.23.0
+
 toolchain go1.24.1
 
 require (
+	filippo.io/age v1.2.1
 	github.com/gopasspw/gopass v1.15.14
 	github.com/josa42/go-prompt v0.0.0-20230119084121-2990edc6a656
 	github.com/manifoldco/promptui v0.9.0
 	github.com/spf13/cobra v1.8.1
 	github.com/stretchr/testify v1.9.0
 	github.com/tobischo/gokeepasslib/v3 v3.6.0
+	golang.org/x/term v0.29.0
 	gopkg.in/errgo.v2 v2.1.0
 	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
-	filippo.io/age v1.2.1 // indirect
 	filippo.io/edwards25519 v1.1.0 // indirect
 	github.com/ProtonMail/go-crypto v1.0.0 // indirect
 	github.com/alessio/shellescape v1.4.2 // indirect
@@ -53,6 +55,5 @@ require (
 	golang.org/x/crypto v0.35.0 // indirect
 	golang.org/x/exp v0.0.0-20240823005443-9b4947da3948 // indirect
 	golang.org/x/sys v0.30.0 // indirect
-	golang.org/x/term v0.29.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,114 @@
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// PromptVariableName is the name of the environment variable containing the
+// prompt when running the password command
+const PromptVariableName = "ENVMANAGER_PROMPT"
+
+// CredentialSources are the non-interactive sources of a secret (e.g. the
+// password of a keepass database). Empty sources are skipped.
+type CredentialSources struct {
+	//Env is the name of an environment variable containing the secret
+	Env string
+	//File is the path of a file containing the secret
+	File string
+	//Command is a shell command printing the secret, like an askpass program
+	Command string
+}
+
+// GetSecret returns the secret from the first source of sources which yields
+// one. An unset environment variable, a missing file or a failing command are
+// skipped. Then the secret is read from stdin (if PasswordStdin is set) or the
+// user is prompted for it. If that fails as well, the error names every source
+// which was tried.
+func (i *input) GetSecret(prompt string, sources CredentialSources) (string, error) {
+	var skipped []string
+	if sources.Env != "" {
+		if secret, exists := os.LookupEnv(sources.Env); exists {
+			return secret, nil
+		}
+		skipped = append(skipped, fmt.Sprintf("the environment variable %s is not set", sources.Env))
+	}
+	if sources.File != "" {
+		data, err := os.ReadFile(sources.File)
+		if err == nil {
+			return trimNewline(string(data)), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read the password file: %w", err)
+		}
+		skipped = append(skipped, fmt.Sprintf("the password file %s does not exist", sources.File))
+	}
+	if sources.Command != "" {
+		secret, err := runPasswordCommand(sources.Command, prompt)
+		if err == nil {
+			return secret, nil
+		}
+		skipped = append(skipped, err.Error())
+	}
+	var secret string
+	var err error
+	if i.PasswordStdin {
+		secret, err = i.readStdinSecret()
+	} else {
+		secret, err = i.PromptPassword(prompt, '*')
+	}
+	if err != nil && len(skipped) > 0 {
+		return "", fmt.Errorf("%s, %w", strings.Join(skipped, ", "), err)
+	}
+	return secret, err
+}
+
+// readStdinSecret reads the secret from stdin. Stdin is only read once, every
+// later call returns the same secret.
+func (i *input) readStdinSecret() (string, error) {
+	if i.stdinSecret == nil {
+		reader := i.stdin
+		if reader == nil {
+			reader = os.Stdin
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the password from stdin: %w", err)
+		}
+		secret := trimNewline(string(data))
+		i.stdinSecret = &secret
+	}
+	return *i.stdinSecret, nil
+}
+
+// runPasswordCommand runs the command with the shell and returns what it
+// printed. The prompt is passed in the variable PromptVariableName. The
+// terminal stays connected to stdin, so the command can ask the user itself.
+func runPasswordCommand(command string, prompt string) (string, error) {
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Env = append(os.Environ(), PromptVariableName+"="+prompt)
+	cmd.Stdin = os.Stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	// stderr is captured as the wrapper would evaluate it
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			return "", fmt.Errorf("the password command failed: %w", err)
+		}
+		return "", fmt.Errorf("the password command failed: %w: %s", err, message)
+	}
+	return trimNewline(stdout.String()), nil
+}
+
+// trimNewline removes one trailing line break, as files and commands usually end
+// with one while secrets do not
+func trimNewline(value string) string {
+	value = strings.TrimSuffix(value, "\n")
+	return strings.TrimSuffix(value, "\r")
+}
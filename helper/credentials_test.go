@@ -0,0 +1,128 @@
+package helper
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestInput_GetSecret(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte("file-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ENVMANAGER_TEST_PASSWORD", "env-secret")
+	tests := []struct {
+		name    string
+		sources CredentialSources
+		inputs  []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Environment variable",
+			sources: CredentialSources{Env: "ENVMANAGER_TEST_PASSWORD"},
+			want:    "env-secret",
+		},
+		{
+			name:    "Missing environment variable falls back to prompt",
+			sources: CredentialSources{Env: "ENVMANAGER_TEST_MISSING"},
+			inputs:  []string{"prompted-secret"},
+			want:    "prompted-secret",
+		},
+		{
+			name:    "Missing environment variable falls back to file",
+			sources: CredentialSources{Env: "ENVMANAGER_TEST_MISSING", File: passwordFile},
+			want:    "file-secret",
+		},
+		{
+			name:    "File",
+			sources: CredentialSources{File: passwordFile},
+			want:    "file-secret",
+		},
+		{
+			name:    "Missing file falls back to command",
+			sources: CredentialSources{File: passwordFile + ".missing", Command: "echo command-secret"},
+			want:    "command-secret",
+		},
+		{
+			name:    "Unreadable file",
+			sources: CredentialSources{File: t.TempDir()},
+			wantErr: true,
+		},
+		{
+			name:    "Command",
+			sources: CredentialSources{Command: `printf '%s\n' "secret for $ENVMANAGER_PROMPT"`},
+			want:    "secret for Enter password",
+		},
+		{
+			name:    "Failing command falls back to prompt",
+			sources: CredentialSources{Command: "exit 1"},
+			inputs:  []string{"prompted-secret"},
+			want:    "prompted-secret",
+		},
+		{
+			name:    "Environment variable before file",
+			sources: CredentialSources{Env: "ENVMANAGER_TEST_PASSWORD", File: passwordFile},
+			want:    "env-secret",
+		},
+		{
+			name:   "Prompt without sources",
+			inputs: []string{"prompted-secret"},
+			want:   "prompted-secret",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &input{Inputs: tt.inputs}
+			got, err := i.GetSecret("Enter password", tt.sources)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetSecret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInput_GetSecret_stdin(t *testing.T) {
+	i := &input{PasswordStdin: true, stdin: strings.NewReader("stdin-secret\r\n")}
+	for range 2 {
+		// stdin is read once, every storage gets the same secret
+		got, err := i.GetSecret("Enter password", CredentialSources{})
+		if err != nil {
+			t.Fatalf("GetSecret() returned error %v", err)
+		}
+		if got != "stdin-secret" {
+			t.Errorf("GetSecret() = %q, want %q", got, "stdin-secret")
+		}
+	}
+}
+
+func TestInput_GetSecret_noSourceYieldsSecret(t *testing.T) {
+	i := &input{PasswordStdin: true, stdin: iotest.ErrReader(errors.New("stdin is closed"))}
+	_, err := i.GetSecret("Enter password", CredentialSources{
+		Env:     "ENVMANAGER_TEST_MISSING",
+		File:    filepath.Join(t.TempDir(), "missing"),
+		Command: "echo 'vault is sealed' >&2; exit 2",
+	})
+	if err == nil {
+		t.Fatal("GetSecret() got no error but wanted one")
+	}
+	for _, want := range []string{"ENVMANAGER_TEST_MISSING", "missing", "vault is sealed", "stdin is closed"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("GetSecret() error = %v, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestRunPasswordCommand_errorContainsStderr(t *testing.T) {
+	_, err := runPasswordCommand("echo 'vault is sealed' >&2; exit 2", "Enter password")
+	if err == nil || !strings.Contains(err.Error(), "vault is sealed") {
+		t.Errorf("runPasswordCommand() error = %v, want it to contain the stderr of the command", err)
+	}
+}
@@ -1,14 +1,27 @@
 package helper
 
 import (
+	"errors"
 	"github.com/manifoldco/promptui"
+	"golang.org/x/term"
+	"io"
+	"os"
 	"sync"
 )
 
+// ErrNoTerminal is returned when prompting without a terminal, e.g. in a cron job
+var ErrNoTerminal = errors.New("no terminal available to ask for input, configure passwordEnv, passwordFile or passwordCommand for the storage or use --password-stdin")
+
 // input is an abstraction layer for retrieving user input. It is to be used as
 // singleton via GetInput.
 type input struct {
 	Inputs []string
+	//PasswordStdin makes GetSecret read the secret from stdin instead of prompting
+	PasswordStdin bool
+	// stdin replaces os.Stdin in tests
+	stdin io.Reader
+	// stdinSecret is the secret read from stdin, stdin can only be read once
+	stdinSecret *string
 }
 
 // inputInstance holds the singleton instance
@@ -58,6 +71,9 @@ func (i *input) PromptPassword(prompt string, mask rune) (string, error) {
 	if i.hasPresetInputValues() {
 		return i.getPresetInputValue(), nil
 	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", ErrNoTerminal
+	}
 	promptUi := promptui.Prompt{
 		Label: prompt,
 		Mask:  mask,
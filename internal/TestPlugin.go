@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"envManager/storagePlugin"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestPluginVariableName is set if the test binary is started as storage adapter plugin
+const TestPluginVariableName = "ENVMANAGER_TEST_PLUGIN"
+
+// TestPlugin is a storage adapter plugin with the entries entry1 and group1/g1e1. Its config must contain valid=true.
+type TestPlugin struct{}
+
+var testPluginEntries = map[string]map[string]string{
+	"entry1":      {"UserName": "user1", "Password": "pass1"},
+	"group1/g1e1": {"UserName": "g1e1-user", "Password": "g1e1-pass"},
+}
+
+func (p TestPlugin) GetEntry(_ string, _ map[string]string, key string) (map[string]string, error) {
+	entry, exists := testPluginEntries[key]
+	if !exists {
+		return nil, fmt.Errorf("could not find entry %s", key)
+	}
+	return entry, nil
+}
+
+func (p TestPlugin) Validate(_ string, config map[string]string) (error, []string) {
+	out := []string{fmt.Sprintf("Option valid is %s", config["valid"])}
+	if config["valid"] != "true" {
+		return errors.New("the config is invalid"), out
+	}
+	return nil, out
+}
+
+func (p TestPlugin) GetDefaultConfig() map[string]string {
+	return map[string]string{"valid": "true"}
+}
+
+func (p TestPlugin) IsCaseSensitive() bool {
+	return false
+}
+
+func (p TestPlugin) ListEntries(_ string, _ map[string]string, prefix string) ([]string, error) {
+	var paths []string
+	for path := range testPluginEntries {
+		if strings.HasPrefix(path, prefix) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// ServeTestPlugin serves the TestPlugin and exits if the test binary was started as plugin by InstallTestPlugin. Call
+// it at the beginning of TestMain.
+func ServeTestPlugin() {
+	if os.Getenv(TestPluginVariableName) != "" {
+		storagePlugin.Serve(TestPlugin{})
+	}
+}
+
+// InstallTestPlugin puts the test binary as plugin for storageType on the PATH and returns the path of the plugin
+func InstallTestPlugin(t *testing.T, storageType string) string {
+	t.Helper()
+	testBinary, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	executable := filepath.Join(dir, storagePlugin.ExecutablePrefix+storageType)
+	if err := os.Symlink(testBinary, executable); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv(TestPluginVariableName, "1")
+	return executable
+}
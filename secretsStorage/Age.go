@@ -0,0 +1,398 @@
+package secretsStorage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"filippo.io/age"
+	"fmt"
+	"gopkg.in/errgo.v2/fmt/errors"
+	"gopkg.in/yaml.v2"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const AgeTypeIdentifier = "age"
+
+// agePrefix and ageSuffix enclose the base64 encoded age ciphertext of an encrypted value
+const agePrefix = "ENC[age,"
+const ageSuffix = "]"
+
+func init() {
+	RegisterStorageAdapterType(AgeTypeIdentifier, newAge, (&Age{}).GetDefaultConfig())
+}
+
+// Age reads a YAML or JSON document whose values are encrypted with age. The key of an entry is the slash separated
+// path of a map in the document, the values of this map are the attributes of the entry. Encrypted values look like
+// ENC[age,<base64 ciphertext>] and are decrypted with the identities in IdentityFile, other values are used as they
+// are.
+type Age struct {
+	Name         string
+	FilePath     string
+	IdentityFile string
+	identities   []age.Identity
+}
+
+// newAge creates an Age storage adapter from its config
+func newAge(name string, config Storage) (StorageAdapter, error) {
+	return &Age{
+		Name:         name,
+		FilePath:     config.Config["path"],
+		IdentityFile: config.Config["identityFile"],
+	}, nil
+}
+
+func (a *Age) GetEntry(key string) (*Entry, error) {
+	data, err := os.ReadFile(a.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", a.FilePath, err)
+	}
+
+	node := document
+	for _, part := range strings.Split(key, "/") {
+		if part == "" {
+			continue
+		}
+		value, exists := getAgeMapValue(node, part)
+		if !exists {
+			return nil, errors.Newf("Could not find %s in %s", key, a.FilePath)
+		}
+		node = value
+	}
+	values, isMap := getAgeMapValues(node)
+	if !isMap {
+		return nil, errors.Newf("%s is not a map in %s", key, a.FilePath)
+	}
+
+	entry := NewEntry()
+	for name, value := range values {
+		if _, isNested := getAgeMapValues(value); isNested {
+			// nested maps are entries on their own
+			continue
+		}
+		if _, isList := value.([]interface{}); isList {
+			continue
+		}
+		decrypted, err := a.decryptValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s of %s: %w", name, key, err)
+		}
+		if err := entry.SetAttribute(name, decrypted); err != nil {
+			return nil, err
+		}
+	}
+	return &entry, nil
+}
+
+// decryptValue returns the plain text of a scalar value of the document
+func (a *Age) decryptValue(value interface{}) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	text := fmt.Sprint(value)
+	if !isAgeEncrypted(text) {
+		return text, nil
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(text[len(agePrefix) : len(text)-len(ageSuffix)])
+	if err != nil {
+		return "", err
+	}
+	identities, err := a.getIdentities()
+	if err != nil {
+		return "", err
+	}
+	reader, err := age.Decrypt(bytes.NewReader(ciphertext), identities...)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// getIdentities reads the identities from IdentityFile once
+func (a *Age) getIdentities() ([]age.Identity, error) {
+	if a.identities != nil {
+		return a.identities, nil
+	}
+	file, err := os.Open(a.IdentityFile)
+	if err != nil {
+		return nil, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer file.Close()
+	identities, err := age.ParseIdentities(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the identity file %s: %w", a.IdentityFile, err)
+	}
+	a.identities = identities
+	return identities, nil
+}
+
+// GetRecipients returns the recipients of the identities in IdentityFile, so documents can be encrypted for them
+func (a *Age) GetRecipients() ([]age.Recipient, error) {
+	identities, err := a.getIdentities()
+	if err != nil {
+		return nil, err
+	}
+	var recipients []age.Recipient
+	for _, identity := range identities {
+		x25519Identity, isX25519 := identity.(*age.X25519Identity)
+		if !isX25519 {
+			continue
+		}
+		recipients = append(recipients, x25519Identity.Recipient())
+	}
+	if len(recipients) == 0 {
+		return nil, errors.Newf("The identity file %s does not contain X25519 identities", a.IdentityFile)
+	}
+	return recipients, nil
+}
+
+func (a *Age) IsCaseSensitive() bool {
+	return true
+}
+
+func (a *Age) Validate() (error, []string) {
+	var out []string
+	validationFailed := false
+
+	fileExists := true
+	_, err := os.Stat(a.FilePath)
+	if err != nil {
+		fileExists = false
+		validationFailed = true
+	}
+	out = append(out, fmt.Sprintf("Configured file is %s\nFile exists: %t", a.FilePath, fileExists))
+
+	if a.IdentityFile == "" {
+		validationFailed = true
+		out = append(out, "No identity file is configured")
+	} else {
+		identityFileExists := true
+		_, err = os.Stat(a.IdentityFile)
+		if err != nil {
+			identityFileExists = false
+			validationFailed = true
+		}
+		out = append(out, fmt.Sprintf("Configured identity file is %s\nIdentity file exists: %t", a.IdentityFile, identityFileExists))
+	}
+
+	if validationFailed {
+		return errors.Newf("Validation of %s failed. Run debug storage %s to check it in detail", a.Name, a.Name), out
+	}
+	return nil, out
+}
+
+func (a *Age) GetDefaultConfig() map[string]string {
+	return map[string]string{
+		"path":         "",
+		"identityFile": "",
+	}
+}
+
+// isAgeEncrypted checks if value is an encrypted value of an age document
+func isAgeEncrypted(value string) bool {
+	return strings.HasPrefix(value, agePrefix) && strings.HasSuffix(value, ageSuffix)
+}
+
+// getAgeMapValue returns the value of key if node is a map
+func getAgeMapValue(node interface{}, key string) (interface{}, bool) {
+	values, isMap := getAgeMapValues(node)
+	if !isMap {
+		return nil, false
+	}
+	value, exists := values[key]
+	return value, exists
+}
+
+// getAgeMapValues returns the values of node by their key if node is a map
+func getAgeMapValues(node interface{}) (map[string]interface{}, bool) {
+	values := map[string]interface{}{}
+	switch typed := node.(type) {
+	case map[interface{}]interface{}:
+		for key, value := range typed {
+			values[fmt.Sprint(key)] = value
+		}
+	case yaml.MapSlice:
+		for _, item := range typed {
+			values[fmt.Sprint(item.Key)] = item.Value
+		}
+	default:
+		return nil, false
+	}
+	return values, true
+}
+
+// ParseAgeRecipients parses age recipients like age1...
+func ParseAgeRecipients(values []string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, value := range values {
+		recipient, err := age.ParseX25519Recipient(value)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
+
+// EncryptAgeDocument encrypts all values of a YAML or JSON document for the recipients, so it can be read by the age
+// storage adapter. Values which are already encrypted are kept, so new values can be added to an encrypted document.
+// The order of the keys is kept, comments are lost.
+func EncryptAgeDocument(data []byte, isJSON bool, recipients []age.Recipient) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("At least one recipient is required")
+	}
+	var document yaml.MapSlice
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	encrypted, err := encryptAgeNode(document, recipients)
+	if err != nil {
+		return nil, err
+	}
+	if isJSON {
+		var buffer bytes.Buffer
+		if err := writeAgeJSON(&buffer, encrypted, ""); err != nil {
+			return nil, err
+		}
+		buffer.WriteString("\n")
+		return buffer.Bytes(), nil
+	}
+	return yaml.Marshal(encrypted)
+}
+
+// encryptAgeNode encrypts all scalar values of node recursively
+func encryptAgeNode(node interface{}, recipients []age.Recipient) (interface{}, error) {
+	switch typed := node.(type) {
+	case nil:
+		return nil, nil
+	case yaml.MapSlice:
+		encrypted := make(yaml.MapSlice, 0, len(typed))
+		for _, item := range typed {
+			value, err := encryptAgeNode(item.Value, recipients)
+			if err != nil {
+				return nil, err
+			}
+			encrypted = append(encrypted, yaml.MapItem{Key: item.Key, Value: value})
+		}
+		return encrypted, nil
+	case map[interface{}]interface{}:
+		encrypted := map[interface{}]interface{}{}
+		for key, value := range typed {
+			encryptedValue, err := encryptAgeNode(value, recipients)
+			if err != nil {
+				return nil, err
+			}
+			encrypted[key] = encryptedValue
+		}
+		return encrypted, nil
+	case []interface{}:
+		encrypted := make([]interface{}, 0, len(typed))
+		for _, value := range typed {
+			encryptedValue, err := encryptAgeNode(value, recipients)
+			if err != nil {
+				return nil, err
+			}
+			encrypted = append(encrypted, encryptedValue)
+		}
+		return encrypted, nil
+	default:
+		text := fmt.Sprint(typed)
+		if isAgeEncrypted(text) {
+			return text, nil
+		}
+		var buffer bytes.Buffer
+		writer, err := age.Encrypt(&buffer, recipients...)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(writer, text); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return agePrefix + base64.StdEncoding.EncodeToString(buffer.Bytes()) + ageSuffix, nil
+	}
+}
+
+// writeAgeJSON writes node as indented JSON, keeping the order of the keys of a yaml.MapSlice
+func writeAgeJSON(buffer *bytes.Buffer, node interface{}, indent string) error {
+	switch typed := node.(type) {
+	case yaml.MapSlice:
+		items := make([]yaml.MapItem, len(typed))
+		copy(items, typed)
+		return writeAgeJSONObject(buffer, items, indent)
+	case map[interface{}]interface{}:
+		items := make([]yaml.MapItem, 0, len(typed))
+		for key, value := range typed {
+			items = append(items, yaml.MapItem{Key: key, Value: value})
+		}
+		sort.Slice(items, func(i, j int) bool {
+			return fmt.Sprint(items[i].Key) < fmt.Sprint(items[j].Key)
+		})
+		return writeAgeJSONObject(buffer, items, indent)
+	case []interface{}:
+		if len(typed) == 0 {
+			buffer.WriteString("[]")
+			return nil
+		}
+		buffer.WriteString("[\n")
+		for i, value := range typed {
+			buffer.WriteString(indent + "  ")
+			if err := writeAgeJSON(buffer, value, indent+"  "); err != nil {
+				return err
+			}
+			if i < len(typed)-1 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(indent + "]")
+		return nil
+	default:
+		encoded, err := json.Marshal(typed)
+		if err != nil {
+			return err
+		}
+		buffer.Write(encoded)
+		return nil
+	}
+}
+
+// writeAgeJSONObject writes the items as indented JSON object
+func writeAgeJSONObject(buffer *bytes.Buffer, items []yaml.MapItem, indent string) error {
+	if len(items) == 0 {
+		buffer.WriteString("{}")
+		return nil
+	}
+	buffer.WriteString("{\n")
+	for i, item := range items {
+		key, err := json.Marshal(fmt.Sprint(item.Key))
+		if err != nil {
+			return err
+		}
+		buffer.WriteString(indent + "  ")
+		buffer.Write(key)
+		buffer.WriteString(": ")
+		if err := writeAgeJSON(buffer, item.Value, indent+"  "); err != nil {
+			return err
+		}
+		if i < len(items)-1 {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString("\n")
+	}
+	buffer.WriteString(indent + "}")
+	return nil
+}
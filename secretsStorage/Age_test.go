@@ -0,0 +1,199 @@
+package secretsStorage
+
+import (
+	"encoding/json"
+	"filippo.io/age"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// createAgeStorage encrypts document for a new identity and returns an Age storage adapter reading it
+func createAgeStorage(t *testing.T, document string, isJSON bool) *Age {
+	t.Helper()
+	dir := t.TempDir()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	identityFile := filepath.Join(dir, "keys.txt")
+	if err := os.WriteFile(identityFile, []byte("# test identity\n"+identity.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := EncryptAgeDocument([]byte(document), isJSON, []age.Recipient{identity.Recipient()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "secrets.yml")
+	if err := os.WriteFile(file, encrypted, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return &Age{Name: "age", FilePath: file, IdentityFile: identityFile}
+}
+
+func TestAge_GetEntry(t *testing.T) {
+	const document = `
+user: root-user
+aws:
+  prod:
+    AWS_ACCESS_KEY_ID: AKIA1
+    AWS_SECRET_ACCESS_KEY: secret1
+    PORT: 8080
+    list:
+      - item
+    nested:
+      KEY: value
+`
+	a := createAgeStorage(t, document, false)
+	tests := []struct {
+		name    string
+		key     string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "Nested map",
+			key:  "aws/prod",
+			want: map[string]string{"AWS_ACCESS_KEY_ID": "AKIA1", "AWS_SECRET_ACCESS_KEY": "secret1", "PORT": "8080"},
+		},
+		{
+			name: "Root map",
+			key:  "",
+			want: map[string]string{"user": "root-user"},
+		},
+		{
+			name: "Deeply nested map",
+			key:  "/aws/prod/nested/",
+			want: map[string]string{"KEY": "value"},
+		},
+		{
+			name:    "Missing map",
+			key:     "aws/dev",
+			wantErr: true,
+		},
+		{
+			name:    "Not a map",
+			key:     "aws/prod/PORT",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.GetEntry(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetEntry() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got.attributes, tt.want) {
+				t.Errorf("GetEntry() got = %v, want %v", got.attributes, tt.want)
+			}
+		})
+	}
+}
+
+func TestAge_GetEntry_plainAndWrongIdentity(t *testing.T) {
+	a := createAgeStorage(t, "entry:\n  KEY: value\n", false)
+	data, err := os.ReadFile(a.FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// plain values are used as they are
+	if err := os.WriteFile(a.FilePath, append(data, []byte("  PLAIN: plain\n")...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := a.GetEntry("entry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"KEY": "value", "PLAIN": "plain"}
+	if !reflect.DeepEqual(entry.attributes, want) {
+		t.Errorf("GetEntry() got = %v, want %v", entry.attributes, want)
+	}
+
+	other := createAgeStorage(t, "{}", false)
+	a.IdentityFile = other.IdentityFile
+	a.identities = nil
+	if _, err := a.GetEntry("entry"); err == nil {
+		t.Errorf("GetEntry() with a wrong identity did not fail")
+	}
+}
+
+func TestEncryptAgeDocument(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipients := []age.Recipient{identity.Recipient()}
+
+	encrypted, err := EncryptAgeDocument([]byte(`{"b": {"KEY": "value", "EMPTY": null}, "a": [1, true]}`), true, recipients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(encrypted, &document); err != nil {
+		t.Fatalf("EncryptAgeDocument() did not return JSON: %v\n%s", err, encrypted)
+	}
+	if strings.Index(string(encrypted), `"b"`) > strings.Index(string(encrypted), `"a"`) {
+		t.Errorf("EncryptAgeDocument() did not keep the order of the keys:\n%s", encrypted)
+	}
+	b := document["b"].(map[string]interface{})
+	if !isAgeEncrypted(b["KEY"].(string)) || b["EMPTY"] != nil {
+		t.Errorf("EncryptAgeDocument() got %v", b)
+	}
+	for _, value := range document["a"].([]interface{}) {
+		if !isAgeEncrypted(value.(string)) {
+			t.Errorf("EncryptAgeDocument() did not encrypt the list item %v", value)
+		}
+	}
+
+	// encrypted values are kept
+	again, err := EncryptAgeDocument(encrypted, true, recipients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(encrypted) {
+		t.Errorf("EncryptAgeDocument() changed encrypted values:\n%s\n%s", encrypted, again)
+	}
+
+	if _, err := EncryptAgeDocument([]byte("KEY: value"), false, nil); err == nil {
+		t.Errorf("EncryptAgeDocument() without recipients did not fail")
+	}
+}
+
+func TestAge_GetRecipients(t *testing.T) {
+	a := createAgeStorage(t, "{}", false)
+	recipients, err := a.GetRecipients()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipients) != 1 {
+		t.Errorf("GetRecipients() got %d recipients, want 1", len(recipients))
+	}
+}
+
+func TestAge_Validate(t *testing.T) {
+	existing := createAgeStorage(t, "{}", false)
+	tests := []struct {
+		name    string
+		age     *Age
+		wantErr bool
+	}{
+		{name: "Valid", age: existing},
+		{name: "Missing file", age: &Age{FilePath: "/nonexistent", IdentityFile: existing.IdentityFile}, wantErr: true},
+		{name: "Missing identity file", age: &Age{FilePath: existing.FilePath, IdentityFile: "/nonexistent"}, wantErr: true},
+		{name: "No identity file", age: &Age{FilePath: existing.FilePath}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err, _ := tt.age.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
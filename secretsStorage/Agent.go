@@ -0,0 +1,292 @@
+package secretsStorage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// AgentSocketVariableName is the name of the environment variable containing the socket of the running agent
+const AgentSocketVariableName = "ENVMANAGER_AGENT_SOCK"
+
+// AgentPidVariableName is the name of the environment variable containing the process id of the running agent
+const AgentPidVariableName = "ENVMANAGER_AGENT_PID"
+
+// actions understood by the agent
+const (
+	agentActionPing     = "ping"
+	agentActionGetEntry = "getEntry"
+	//agentActionListEntries lists the entries starting with the key of the request
+	agentActionListEntries = "listEntries"
+	//agentActionSetEntry sets the attributes of the request on the entry with the key of the request
+	agentActionSetEntry = "setEntry"
+	agentActionUnlock   = "unlock"
+	agentActionLock     = "lock"
+	agentActionStop     = "stop"
+)
+
+// agentRequest is sent by the AgentClient, one JSON object per line
+type agentRequest struct {
+	Action  string   `json:"action"`
+	Storage string   `json:"storage,omitempty"`
+	Config  *Storage `json:"config,omitempty"`
+	Key     string   `json:"key,omitempty"`
+	Secret  string   `json:"secret,omitempty"`
+	//Attributes are the attributes to set for agentActionSetEntry
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// agentResponse is the answer of the agent to an agentRequest
+type agentResponse struct {
+	Error string `json:"error,omitempty"`
+	//Locked is true if the storage must be unlocked before the entry can be retrieved
+	Locked     bool              `json:"locked,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	//BinaryAttributes hold the attributes which are not valid UTF-8, e.g. keepass attachments. JSON strings would
+	//replace the invalid bytes, a []byte is written base64 encoded.
+	BinaryAttributes map[string][]byte `json:"binaryAttributes,omitempty"`
+	//AttributeErrors hold the errors of the attributes which could not be read, see Entry.attributeErrors
+	AttributeErrors map[string]string `json:"attributeErrors,omitempty"`
+	Entries         []string          `json:"entries,omitempty"`
+}
+
+// agentStorage is a storage adapter held by the agent and the config it was created from
+type agentStorage struct {
+	config  Storage
+	adapter LockableStorageAdapter
+}
+
+// Agent keeps lockable storage adapters unlocked between invocations of envManager. The storage adapters are created
+// from the config sent by the clients, so the agent does not read any config files. All storages are locked once the
+// agent was idle for the idle timeout.
+type Agent struct {
+	idleTimeout time.Duration
+	mutex       sync.Mutex
+	storages    map[string]agentStorage
+	idleTimer   *time.Timer
+	lastUsed    time.Time
+	stopped     chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewAgent creates an agent which locks all storages after being idle for idleTimeout. An idleTimeout of 0 disables
+// locking on idle.
+func NewAgent(idleTimeout time.Duration) *Agent {
+	return &Agent{
+		idleTimeout: idleTimeout,
+		storages:    map[string]agentStorage{},
+		stopped:     make(chan struct{}),
+	}
+}
+
+// Serve handles the connections of listener until the agent is stopped. The listener is closed on return.
+func (a *Agent) Serve(listener net.Listener) error {
+	go func() {
+		<-a.stopped
+		_ = listener.Close()
+	}()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-a.stopped:
+				return nil
+			default:
+				a.Stop()
+				return err
+			}
+		}
+		go a.handleConnection(conn)
+	}
+}
+
+// Stop locks all storages and makes Serve return
+func (a *Agent) Stop() {
+	a.stopOnce.Do(func() {
+		a.LockAll()
+		close(a.stopped)
+	})
+}
+
+// LockAll locks all storages held by the agent
+func (a *Agent) LockAll() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for _, storage := range a.storages {
+		storage.adapter.Lock()
+	}
+}
+
+// handleConnection answers the requests of one client
+func (a *Agent) handleConnection(conn net.Conn) {
+	//goland:noinspection GoUnhandledErrorResult
+	defer conn.Close()
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+	for {
+		var request agentRequest
+		if err := decoder.Decode(&request); err != nil {
+			return
+		}
+		response := a.handleRequest(request)
+		if err := encoder.Encode(response); err != nil {
+			return
+		}
+		if request.Action == agentActionStop {
+			a.Stop()
+			return
+		}
+	}
+}
+
+// handleRequest executes a single request
+func (a *Agent) handleRequest(request agentRequest) agentResponse {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	// the idle time starts after the request, unlocking can take a while
+	defer a.resetIdleTimer()
+
+	switch request.Action {
+	case agentActionPing, agentActionStop:
+		return agentResponse{}
+	case agentActionLock:
+		for _, storage := range a.storages {
+			storage.adapter.Lock()
+		}
+		return agentResponse{}
+	case agentActionGetEntry, agentActionListEntries, agentActionSetEntry:
+		adapter, err := a.getAdapter(request)
+		if err != nil {
+			return agentResponse{Error: err.Error()}
+		}
+		if adapter.IsLocked() {
+			if adapter.GetUnlockPrompt() != "" {
+				// the client asks for the secret, the agent has no terminal
+				return agentResponse{Locked: true}
+			}
+			// no secret required, e.g. a database secured with a key file only
+			if err := adapter.Unlock(""); err != nil {
+				return agentResponse{Error: err.Error()}
+			}
+		}
+		if request.Action == agentActionListEntries {
+			lister, isLister := adapter.(EntryLister)
+			if !isLister {
+				return agentResponse{Error: fmt.Sprintf("storage %s cannot list its entries", request.Storage)}
+			}
+			entries, err := lister.ListEntries(request.Key)
+			if err != nil {
+				return agentResponse{Error: err.Error()}
+			}
+			return agentResponse{Entries: entries}
+		}
+		if request.Action == agentActionSetEntry {
+			writer, isWriter := adapter.(EntryWriter)
+			if !isWriter {
+				return agentResponse{Error: fmt.Sprintf("storage %s cannot write entries", request.Storage)}
+			}
+			entry := NewEntry()
+			for name, value := range request.Attributes {
+				if err := entry.SetAttribute(name, value); err != nil {
+					return agentResponse{Error: err.Error()}
+				}
+			}
+			if err := writer.SetEntry(request.Key, &entry); err != nil {
+				return agentResponse{Error: err.Error()}
+			}
+			return agentResponse{}
+		}
+		entry, err := adapter.GetEntry(request.Key)
+		if err != nil {
+			return agentResponse{Error: err.Error()}
+		}
+		return newEntryResponse(entry)
+	case agentActionUnlock:
+		adapter, err := a.getAdapter(request)
+		if err != nil {
+			return agentResponse{Error: err.Error()}
+		}
+		if err := adapter.Unlock(request.Secret); err != nil {
+			return agentResponse{Error: err.Error()}
+		}
+		return agentResponse{}
+	default:
+		return agentResponse{Error: fmt.Sprintf("unknown action %s", request.Action)}
+	}
+}
+
+// getAdapter returns the storage adapter of the request. It is created if the agent does not know it yet or if its
+// config changed. Must be called with the mutex locked.
+func (a *Agent) getAdapter(request agentRequest) (LockableStorageAdapter, error) {
+	if request.Config == nil {
+		return nil, errors.New("the request does not contain the config of the storage")
+	}
+	storage, exists := a.storages[request.Storage]
+	if exists && reflect.DeepEqual(storage.config, *request.Config) {
+		return storage.adapter, nil
+	}
+	adapter, err := createStorageAdapter(request.Storage, *request.Config)
+	if err != nil {
+		return nil, err
+	}
+	lockable, isLockable := adapter.(LockableStorageAdapter)
+	if !isLockable {
+		return nil, fmt.Errorf("storage %s cannot be held by the agent", request.Storage)
+	}
+	a.storages[request.Storage] = agentStorage{config: *request.Config, adapter: lockable}
+	return lockable, nil
+}
+
+// resetIdleTimer restarts the countdown to locking all storages. Must be called with the mutex locked.
+func (a *Agent) resetIdleTimer() {
+	if a.idleTimeout <= 0 {
+		return
+	}
+	a.lastUsed = time.Now()
+	if a.idleTimer == nil {
+		a.idleTimer = time.AfterFunc(a.idleTimeout, a.lockIfIdle)
+		return
+	}
+	a.idleTimer.Reset(a.idleTimeout)
+}
+
+// lockIfIdle locks all storages if the agent was idle for the idle timeout. The timer may fire while a request is
+// handled, in this case the countdown starts again.
+func (a *Agent) lockIfIdle() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if idle := time.Since(a.lastUsed); idle < a.idleTimeout {
+		a.idleTimer.Reset(a.idleTimeout - idle)
+		return
+	}
+	for _, storage := range a.storages {
+		storage.adapter.Lock()
+	}
+}
+
+// newEntryResponse creates the response holding the attributes of entry
+func newEntryResponse(entry *Entry) agentResponse {
+	response := agentResponse{Attributes: map[string]string{}}
+	for name, err := range entry.attributeErrors {
+		if response.AttributeErrors == nil {
+			response.AttributeErrors = map[string]string{}
+		}
+		response.AttributeErrors[name] = err.Error()
+	}
+	for name, value := range entry.readableAttributes() {
+		if utf8.ValidString(value) {
+			response.Attributes[name] = value
+			continue
+		}
+		if response.BinaryAttributes == nil {
+			response.BinaryAttributes = map[string][]byte{}
+		}
+		response.BinaryAttributes[name] = []byte(value)
+	}
+	return response
+}
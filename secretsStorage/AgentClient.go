@@ -0,0 +1,195 @@
+package secretsStorage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// errAgentUnreachable is returned if there is no agent listening on the socket
+var errAgentUnreachable = errors.New("the agent is not reachable")
+
+// AgentClient talks to an Agent listening on a unix socket
+type AgentClient struct {
+	socketPath string
+}
+
+// NewAgentClient creates a client for the agent listening on socketPath
+func NewAgentClient(socketPath string) *AgentClient {
+	return &AgentClient{socketPath: socketPath}
+}
+
+// Ping checks if the agent is reachable
+func (c *AgentClient) Ping() error {
+	_, err := c.request(agentRequest{Action: agentActionPing})
+	return err
+}
+
+// Lock makes the agent lock all storages
+func (c *AgentClient) Lock() error {
+	_, err := c.request(agentRequest{Action: agentActionLock})
+	return err
+}
+
+// Stop makes the agent lock all storages and exit
+func (c *AgentClient) Stop() error {
+	_, err := c.request(agentRequest{Action: agentActionStop})
+	return err
+}
+
+// getEntry retrieves an entry of the storage through the agent. If the storage is locked, the secret to unlock it is
+// taken from promptSecret.
+func (c *AgentClient) getEntry(storageName string, config Storage, key string, promptSecret func() (string, error)) (*Entry, error) {
+	request := agentRequest{Action: agentActionGetEntry, Storage: storageName, Config: &config, Key: key}
+	response, err := c.requestUnlocked(request, promptSecret)
+	if err != nil {
+		return nil, err
+	}
+	entry := NewEntry()
+	for name, value := range response.Attributes {
+		if err := entry.SetAttribute(name, value); err != nil {
+			return nil, err
+		}
+	}
+	for name, value := range response.BinaryAttributes {
+		if err := entry.SetAttribute(name, string(value)); err != nil {
+			return nil, err
+		}
+	}
+	for name, message := range response.AttributeErrors {
+		entry.setAttributeError(name, "", errors.New(message))
+	}
+	return &entry, nil
+}
+
+// listEntries lists the entries of the storage starting with prefix through the agent, see getEntry
+func (c *AgentClient) listEntries(storageName string, config Storage, prefix string, promptSecret func() (string, error)) ([]string, error) {
+	request := agentRequest{Action: agentActionListEntries, Storage: storageName, Config: &config, Key: prefix}
+	response, err := c.requestUnlocked(request, promptSecret)
+	if err != nil {
+		return nil, err
+	}
+	return response.Entries, nil
+}
+
+// setEntry sets the attributes of entry on the entry of the storage through the agent, see getEntry
+func (c *AgentClient) setEntry(storageName string, config Storage, key string, entry *Entry, promptSecret func() (string, error)) error {
+	request := agentRequest{Action: agentActionSetEntry, Storage: storageName, Config: &config, Key: key, Attributes: entry.attributes}
+	_, err := c.requestUnlocked(request, promptSecret)
+	return err
+}
+
+// requestUnlocked sends a request which requires the storage to be unlocked. If the agent reports the storage as
+// locked, it is unlocked with the secret from promptSecret and the request is sent again.
+func (c *AgentClient) requestUnlocked(request agentRequest, promptSecret func() (string, error)) (*agentResponse, error) {
+	response, err := c.request(request)
+	if err != nil {
+		return nil, err
+	}
+	if !response.Locked {
+		return response, nil
+	}
+	secret, err := promptSecret()
+	if err != nil {
+		return nil, err
+	}
+	unlockRequest := agentRequest{Action: agentActionUnlock, Storage: request.Storage, Config: request.Config, Secret: secret}
+	if _, err := c.request(unlockRequest); err != nil {
+		return nil, err
+	}
+	if response, err = c.request(request); err != nil {
+		return nil, err
+	}
+	if response.Locked {
+		return nil, fmt.Errorf("storage %s is still locked", request.Storage)
+	}
+	return response, nil
+}
+
+// request sends a single request to the agent. Errors reported by the agent are returned as error.
+func (c *AgentClient) request(request agentRequest) (*agentResponse, error) {
+	conn, err := net.DialTimeout("unix", c.socketPath, time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errAgentUnreachable, err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer conn.Close()
+	// unlocking can take a while, depending on the key derivation of the storage
+	_ = conn.SetDeadline(time.Now().Add(time.Minute))
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return nil, err
+	}
+	var response agentResponse
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return nil, err
+	}
+	if response.Error != "" {
+		return nil, errors.New(response.Error)
+	}
+	return &response, nil
+}
+
+// agentStorageAdapter retrieves the entries of a lockable storage adapter through the agent, so the storage stays
+// unlocked between invocations of envManager. Everything else is handled by the local storage adapter, which is also
+// used if the agent is not reachable (e.g. it was stopped).
+type agentStorageAdapter struct {
+	LockableStorageAdapter
+	name   string
+	config Storage
+	client *AgentClient
+}
+
+func (a *agentStorageAdapter) GetEntry(key string) (*Entry, error) {
+	entry, err := a.client.getEntry(a.name, a.config, key, a.PromptSecret)
+	if errors.Is(err, errAgentUnreachable) {
+		return a.LockableStorageAdapter.GetEntry(key)
+	}
+	return entry, err
+}
+
+// ListEntries lists the entries through the agent, if the local storage adapter can list its entries
+func (a *agentStorageAdapter) ListEntries(prefix string) ([]string, error) {
+	lister, isLister := a.LockableStorageAdapter.(EntryLister)
+	if !isLister {
+		return nil, fmt.Errorf("storage %s cannot list its entries", a.name)
+	}
+	entries, err := a.client.listEntries(a.name, a.config, prefix, a.PromptSecret)
+	if errors.Is(err, errAgentUnreachable) {
+		return lister.ListEntries(prefix)
+	}
+	return entries, err
+}
+
+// SetEntry writes the entry through the agent, so the agent does not keep an outdated copy of the storage
+func (a *agentStorageAdapter) SetEntry(key string, entry *Entry) error {
+	writer, isWriter := a.LockableStorageAdapter.(EntryWriter)
+	if !isWriter {
+		return fmt.Errorf("storage %s cannot write entries", a.name)
+	}
+	err := a.client.setEntry(a.name, a.config, key, entry, a.PromptSecret)
+	if errors.Is(err, errAgentUnreachable) {
+		return writer.SetEntry(key, entry)
+	}
+	return err
+}
+
+// ListEntriesWithoutPrompt lists the entries of storage starting with prefix without asking for a secret, e.g. for
+// shell completion. Locked storages are listed only if the agent keeps them unlocked.
+func ListEntriesWithoutPrompt(storage StorageAdapter, prefix string) ([]string, error) {
+	lister, isLister := storage.(EntryLister)
+	if !isLister {
+		return nil, errors.New("the storage cannot list its entries")
+	}
+	noPrompt := func() (string, error) {
+		return "", errors.New("the storage is locked")
+	}
+	if proxy, isProxy := storage.(*agentStorageAdapter); isProxy {
+		return proxy.client.listEntries(proxy.name, proxy.config, prefix, noPrompt)
+	}
+	if lockable, isLockable := storage.(LockableStorageAdapter); isLockable && lockable.IsLocked() && lockable.GetUnlockPrompt() != "" {
+		return nil, errors.New("the storage is locked")
+	}
+	return lister.ListEntries(prefix)
+}
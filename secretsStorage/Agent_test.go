@@ -0,0 +1,256 @@
+package secretsStorage
+
+import (
+	"envManager/helper"
+	"envManager/internal"
+	"github.com/tobischo/gokeepasslib/v3"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTestAgent starts an agent on a socket in a temporary directory and returns the socket path
+func startTestAgent(t *testing.T, idleTimeout time.Duration) (*Agent, string) {
+	t.Helper()
+	socketPath := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen on %s: %v", socketPath, err)
+	}
+	agent := NewAgent(idleTimeout)
+	served := make(chan error, 1)
+	go func() {
+		served <- agent.Serve(listener)
+	}()
+	t.Cleanup(func() {
+		agent.Stop()
+		if err := <-served; err != nil {
+			t.Errorf("Serve() returned error %v", err)
+		}
+	})
+	return agent, socketPath
+}
+
+// assertEntryAttribute checks an attribute of an entry retrieved from storage
+func assertEntryAttribute(t *testing.T, storage StorageAdapter, key string, attribute string, want string) {
+	t.Helper()
+	entry, err := storage.GetEntry(key)
+	if err != nil {
+		t.Fatalf("GetEntry() returned error %v", err)
+	}
+	got, err := entry.GetAttribute(attribute)
+	if err != nil {
+		t.Fatalf("GetAttribute() returned error %v", err)
+	}
+	if *got != want {
+		t.Errorf("GetAttribute() = %v, want %v", *got, want)
+	}
+}
+
+func TestAgent_keepsStorageUnlocked(t *testing.T) {
+	_, socketPath := startTestAgent(t, 0)
+	t.Setenv(AgentSocketVariableName, socketPath)
+	config := Storage{
+		StorageType: KeepassTypeIdentifier,
+		Config:      map[string]string{"path": internal.GetTestDataFile(t, "keepass.kdbx")},
+	}
+
+	storage, err := CreateStorageAdapter("keepass01", config)
+	if err != nil {
+		t.Fatalf("CreateStorageAdapter() returned error %v", err)
+	}
+	if _, isProxy := storage.(*agentStorageAdapter); !isProxy {
+		t.Fatalf("CreateStorageAdapter() returned %T, want a proxy through the agent", storage)
+	}
+	helper.GetInput().Inputs = []string{"1234"}
+	assertEntryAttribute(t, storage, "entry1", "UserName", "user1")
+
+	// a new invocation of envManager creates a new storage adapter, the agent is still unlocked
+	helper.GetInput().Inputs = nil
+	storage, _ = CreateStorageAdapter("keepass01", config)
+	assertEntryAttribute(t, storage, "group1/g1e1", "UserName", "g1e1-user")
+
+	// after locking, the password is required again
+	if err := NewAgentClient(socketPath).Lock(); err != nil {
+		t.Fatalf("Lock() returned error %v", err)
+	}
+	helper.GetInput().Inputs = []string{"wrong password"}
+	if _, err := storage.GetEntry("entry1"); err == nil {
+		t.Error("GetEntry() with wrong password got no error but wanted one")
+	}
+	helper.GetInput().Inputs = []string{"1234"}
+	assertEntryAttribute(t, storage, "entry1", "UserName", "user1")
+}
+
+func TestAgent_idleTimeout(t *testing.T) {
+	agent, socketPath := startTestAgent(t, 300*time.Millisecond)
+	config := Storage{
+		StorageType: KeepassTypeIdentifier,
+		Config:      map[string]string{"path": internal.GetTestDataFile(t, "keepass.kdbx")},
+	}
+	helper.GetInput().Inputs = []string{"1234"}
+	if _, err := NewAgentClient(socketPath).getEntry("keepass01", config, "entry1", func() (string, error) {
+		return "1234", nil
+	}); err != nil {
+		t.Fatalf("getEntry() returned error %v", err)
+	}
+
+	time.Sleep(time.Second)
+	agent.mutex.Lock()
+	defer agent.mutex.Unlock()
+	if !agent.storages["keepass01"].adapter.IsLocked() {
+		t.Error("The storage is still unlocked after the idle timeout")
+	}
+}
+
+func TestAgentClient_Stop(t *testing.T) {
+	agent, socketPath := startTestAgent(t, 0)
+	if err := NewAgentClient(socketPath).Stop(); err != nil {
+		t.Fatalf("Stop() returned error %v", err)
+	}
+	select {
+	case <-agent.stopped:
+	case <-time.After(time.Second):
+		t.Fatal("The agent did not stop")
+	}
+	if err := NewAgentClient(socketPath).Ping(); err == nil {
+		t.Error("Ping() of a stopped agent got no error but wanted one")
+	}
+}
+
+func TestAgentStorageAdapter_fallbackWithoutAgent(t *testing.T) {
+	t.Setenv(AgentSocketVariableName, filepath.Join(t.TempDir(), "missing.sock"))
+	storage, err := CreateStorageAdapter("keepass01", Storage{
+		StorageType: KeepassTypeIdentifier,
+		Config:      map[string]string{"path": internal.GetTestDataFile(t, "keepass.kdbx")},
+	})
+	if err != nil {
+		t.Fatalf("CreateStorageAdapter() returned error %v", err)
+	}
+	helper.GetInput().Inputs = []string{"1234"}
+	assertEntryAttribute(t, storage, "entry1", "UserName", "user1")
+}
+
+func TestAgent_unknownAction(t *testing.T) {
+	_, socketPath := startTestAgent(t, 0)
+	if _, err := NewAgentClient(socketPath).request(agentRequest{Action: "unknown"}); err == nil {
+		t.Error("request() with unknown action got no error but wanted one")
+	}
+}
+
+func TestAgent_listEntries(t *testing.T) {
+	_, socketPath := startTestAgent(t, 0)
+	t.Setenv(AgentSocketVariableName, socketPath)
+	storage, err := CreateStorageAdapter("keepass01", Storage{
+		StorageType: KeepassTypeIdentifier,
+		Config:      map[string]string{"path": internal.GetTestDataFile(t, "keepass.kdbx")},
+	})
+	if err != nil {
+		t.Fatalf("CreateStorageAdapter() returned error %v", err)
+	}
+	lister, isLister := storage.(EntryLister)
+	if !isLister {
+		t.Fatalf("CreateStorageAdapter() returned %T, want an EntryLister", storage)
+	}
+
+	helper.GetInput().Inputs = []string{"1234"}
+	got, err := lister.ListEntries("group1/")
+	if err != nil {
+		t.Fatalf("ListEntries() returned error %v", err)
+	}
+	if len(got) != 1 || got[0] != "group1/g1e1" {
+		t.Errorf("ListEntries() = %v, want [group1/g1e1]", got)
+	}
+
+	// the agent is unlocked now, entries are listed without a password
+	helper.GetInput().Inputs = nil
+	if got, err := ListEntriesWithoutPrompt(storage, ""); err != nil || len(got) != 2 {
+		t.Errorf("ListEntriesWithoutPrompt() = %v, %v, want 2 entries", got, err)
+	}
+	if err := NewAgentClient(socketPath).Lock(); err != nil {
+		t.Fatalf("Lock() returned error %v", err)
+	}
+	if _, err := ListEntriesWithoutPrompt(storage, ""); err == nil {
+		t.Error("ListEntriesWithoutPrompt() of a locked storage got no error but wanted one")
+	}
+}
+
+func TestAgent_setEntry(t *testing.T) {
+	_, socketPath := startTestAgent(t, 0)
+	t.Setenv(AgentSocketVariableName, socketPath)
+	data, err := os.ReadFile(internal.GetTestDataFile(t, "keepass.kdbx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	databaseFile := filepath.Join(t.TempDir(), "keepass.kdbx")
+	if err := os.WriteFile(databaseFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := Storage{StorageType: KeepassTypeIdentifier, Config: map[string]string{"path": databaseFile}}
+	storage, err := CreateStorageAdapter("keepass01", config)
+	if err != nil {
+		t.Fatalf("CreateStorageAdapter() returned error %v", err)
+	}
+
+	entry := NewEntry()
+	_ = entry.SetAttribute("UserName", "rotated")
+	helper.GetInput().Inputs = []string{"1234"}
+	if err := storage.(EntryWriter).SetEntry("entry1", &entry); err != nil {
+		t.Fatalf("SetEntry() returned error %v", err)
+	}
+
+	// the agent wrote the entry, so it knows the new value without asking for the password again
+	helper.GetInput().Inputs = nil
+	storage, _ = CreateStorageAdapter("keepass01", config)
+	assertEntryAttribute(t, storage, "entry1", "UserName", "rotated")
+}
+
+func TestAgent_binaryAttachment(t *testing.T) {
+	_, socketPath := startTestAgent(t, 0)
+	t.Setenv(AgentSocketVariableName, socketPath)
+	// not valid UTF-8, e.g. a DER encoded key
+	const keyStore = "\x30\x82\xff\x00\x01"
+	databaseFile := writeTestDatabase(t, gokeepasslib.WithDatabaseKDBXVersion4(), func(database *gokeepasslib.Database) {
+		entry := newTestEntry(map[string]string{"Title": "service"})
+		entry.Binaries = append(entry.Binaries, database.AddBinary([]byte(keyStore)).CreateReference("key.der"))
+		root := &database.Content.Root.Groups[0]
+		root.Entries = append(root.Entries, entry)
+	})
+	storage, err := CreateStorageAdapter("binary", Storage{
+		StorageType: KeepassTypeIdentifier,
+		Config:      map[string]string{"path": databaseFile},
+	})
+	if err != nil {
+		t.Fatalf("CreateStorageAdapter() returned error %v", err)
+	}
+
+	helper.GetInput().Inputs = []string{"1234"}
+	assertEntryAttribute(t, storage, "service", "attachment:key.der", keyStore)
+}
+
+func TestAgent_attributeErrors(t *testing.T) {
+	_, socketPath := startTestAgent(t, 0)
+	t.Setenv(AgentSocketVariableName, socketPath)
+	databaseFile := writeTestDatabase(t, gokeepasslib.WithDatabaseKDBXVersion4(), func(database *gokeepasslib.Database) {
+		entry := newTestEntry(map[string]string{"Title": "service", "Password": "pass", "Notes": "{REF:N@T:missing}"})
+		root := &database.Content.Root.Groups[0]
+		root.Entries = append(root.Entries, entry)
+	})
+	storage, err := CreateStorageAdapter("attributeErrors", Storage{
+		StorageType: KeepassTypeIdentifier,
+		Config:      map[string]string{"path": databaseFile},
+	})
+	if err != nil {
+		t.Fatalf("CreateStorageAdapter() returned error %v", err)
+	}
+
+	helper.GetInput().Inputs = []string{"1234"}
+	assertEntryAttribute(t, storage, "service", "Password", "pass")
+	entry, _ := storage.GetEntry("service")
+	if _, err := entry.GetAttribute("Notes"); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("GetAttribute(Notes) error = %v, want the error of the reference", err)
+	}
+}
@@ -11,10 +11,10 @@ import (
 )
 
 type Configuration struct {
-	Options          Options             `yaml:"options,omitempty"`
-	Storages         map[string]Storage  `yaml:"storages"`
-	Profiles         map[string]Profile  `yaml:"profiles"`
-	DirectoryMapping map[string][]string `yaml:"directoryMapping"`
+	Options          Options                     `yaml:"options,omitempty"`
+	Storages         map[string]Storage          `yaml:"storages"`
+	Profiles         map[string]Profile          `yaml:"profiles"`
+	DirectoryMapping map[string]DirectoryMapping `yaml:"directoryMapping"`
 }
 
 type Storage struct {
@@ -46,7 +46,7 @@ func NewConfiguration() Configuration {
 		Options:          Options{},
 		Storages:         map[string]Storage{},
 		Profiles:         map[string]Profile{},
-		DirectoryMapping: map[string][]string{},
+		DirectoryMapping: map[string]DirectoryMapping{},
 	}
 }
 
@@ -14,7 +14,7 @@ func TestConfiguration_LoadFromFile(t *testing.T) {
 	type fields struct {
 		Storages         map[string]Storage
 		Profiles         map[string]Profile
-		DirectoryMapping map[string][]string
+		DirectoryMapping map[string]DirectoryMapping
 	}
 	type args struct {
 		path string
@@ -86,8 +86,8 @@ func TestConfiguration_LoadFromFile(t *testing.T) {
 						DependsOn: []string{"root"},
 					},
 				},
-				DirectoryMapping: map[string][]string{
-					"/tmp/projectA": {"prof1", "root"},
+				DirectoryMapping: map[string]DirectoryMapping{
+					"/tmp/projectA": {Profiles: []string{"prof1", "root"}},
 				},
 			},
 		},
@@ -111,6 +111,9 @@ func TestConfiguration_LoadFromFile(t *testing.T) {
 			if !reflect.DeepEqual(tt.want.Profiles, c.Profiles) {
 				t.Errorf("LoadFromFile() got = %v, want %v", c.Profiles, tt.want.Profiles)
 			}
+			if !reflect.DeepEqual(tt.want.DirectoryMapping, c.DirectoryMapping) {
+				t.Errorf("LoadFromFile() got = %v, want %v", c.DirectoryMapping, tt.want.DirectoryMapping)
+			}
 		})
 	}
 }
@@ -119,7 +122,7 @@ func TestConfiguration_WriteToFile(t *testing.T) {
 	type fields struct {
 		Storages         map[string]Storage
 		Profiles         map[string]Profile
-		DirectoryMapping map[string][]string
+		DirectoryMapping map[string]DirectoryMapping
 	}
 	type args struct {
 		path    string
@@ -164,8 +167,8 @@ func TestConfiguration_WriteToFile(t *testing.T) {
 				},
 			},
 		},
-		DirectoryMapping: map[string][]string{
-			"/tmp/projectA": {"profile1"},
+		DirectoryMapping: map[string]DirectoryMapping{
+			"/tmp/projectA": {Profiles: []string{"profile1"}},
 		},
 	}
 
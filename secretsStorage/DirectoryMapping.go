@@ -0,0 +1,141 @@
+package secretsStorage
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DirectoryMapping links profiles to a directory. In the config file, it is either written as a list of profile names
+// or as an object with the keys profiles and exact.
+type DirectoryMapping struct {
+	//Profiles are the names of the profiles to load
+	Profiles []string `yaml:"profiles"`
+	//Exact limits the mapping to the directory itself, without it, the mapping applies to subdirectories as well
+	Exact bool `yaml:"exact,omitempty"`
+}
+
+// UnmarshalYAML accepts the short form (a list of profile names) as well as the long form of a mapping
+func (m *DirectoryMapping) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var profiles []string
+	if err := unmarshal(&profiles); err == nil {
+		*m = DirectoryMapping{Profiles: profiles}
+		return nil
+	}
+	// a named type without the UnmarshalYAML method, otherwise this would recurse
+	type plainMapping DirectoryMapping
+	var mapping plainMapping
+	if err := unmarshal(&mapping); err != nil {
+		return err
+	}
+	*m = DirectoryMapping(mapping)
+	return nil
+}
+
+// MarshalYAML writes the short form unless the long form is required to keep the options of the mapping
+func (m DirectoryMapping) MarshalYAML() (interface{}, error) {
+	if !m.Exact {
+		return m.Profiles, nil
+	}
+	type plainMapping DirectoryMapping
+	return plainMapping(m), nil
+}
+
+// DirectoryMappingMatch describes how a directory mapping relates to a directory
+type DirectoryMappingMatch struct {
+	//Path is the path (or pattern) of the mapping as it was added to the registry
+	Path string
+	//Profiles are the profiles of the mapping
+	Profiles []string
+	//Exact is true if the mapping only applies to the directory itself
+	Exact bool
+	//Matched is true if the mapping applies to the directory
+	Matched bool
+	//MatchedDirectory is the directory the mapping matched, either the directory itself or one of its parents
+	MatchedDirectory string
+	//Reason explains why the mapping matched or why it did not
+	Reason string
+
+	// distance is the number of directories between the directory and MatchedDirectory
+	distance int
+}
+
+// isGlobPattern checks if path contains characters with a special meaning for filepath.Match
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// matchDirectoryMapping checks if the mapping at path applies to directory. Without exact, the mapping applies to all
+// subdirectories of the mapped directory (or the directories matching the pattern). The returned match is always
+// filled, so it can be used to explain why a mapping did not match.
+func matchDirectoryMapping(path string, profiles []string, exact bool, directory string) DirectoryMappingMatch {
+	match := DirectoryMappingMatch{Path: path, Profiles: profiles, Exact: exact}
+	pattern := filepath.Clean(path)
+	isGlob := isGlobPattern(pattern)
+	kind := "the mapped directory"
+	if isGlob {
+		kind = "the pattern"
+	}
+
+	current := filepath.Clean(directory)
+	for distance := 0; ; distance++ {
+		var matches bool
+		if isGlob {
+			// a malformed pattern is reported as ErrBadPattern and treated as not matching
+			matches, _ = filepath.Match(pattern, current)
+		} else {
+			matches = pattern == current
+		}
+		if matches {
+			match.Matched = true
+			match.MatchedDirectory = current
+			match.distance = distance
+			switch {
+			case distance == 0 && isGlob:
+				match.Reason = "the directory matches the pattern"
+			case distance == 0:
+				match.Reason = "the directory is the mapped directory"
+			case isGlob:
+				match.Reason = fmt.Sprintf("the parent directory %s matches the pattern", current)
+			default:
+				match.Reason = fmt.Sprintf("the parent directory %s is the mapped directory", current)
+			}
+			return match
+		}
+		if exact {
+			match.Reason = fmt.Sprintf("the directory does not match %s and the mapping is exact", kind)
+			return match
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		current = parent
+	}
+	match.Reason = fmt.Sprintf("neither the directory nor one of its parents matches %s", kind)
+	return match
+}
+
+// sortDirectoryMappingMatches sorts the matches by precedence, the first one wins. The mapping of the nearest directory
+// wins, a literal path beats a pattern for the same directory and a longer pattern beats a shorter one. Mappings which
+// did not match come last.
+func sortDirectoryMappingMatches(matches []DirectoryMappingMatch) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Matched != b.Matched {
+			return a.Matched
+		}
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		aIsGlob, bIsGlob := isGlobPattern(a.Path), isGlobPattern(b.Path)
+		if aIsGlob != bIsGlob {
+			return !aIsGlob
+		}
+		if len(a.Path) != len(b.Path) {
+			return len(a.Path) > len(b.Path)
+		}
+		return a.Path < b.Path
+	})
+}
@@ -0,0 +1,79 @@
+package secretsStorage
+
+import (
+	"gopkg.in/yaml.v2"
+	"reflect"
+	"testing"
+)
+
+func TestDirectoryMapping_UnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    DirectoryMapping
+		wantErr bool
+	}{
+		{
+			name: "Short form",
+			data: "[prof1, root]",
+			want: DirectoryMapping{Profiles: []string{"prof1", "root"}},
+		},
+		{
+			name: "Long form",
+			data: "{profiles: [prof1, root], exact: true}",
+			want: DirectoryMapping{Profiles: []string{"prof1", "root"}, Exact: true},
+		},
+		{
+			name: "Long form without exact",
+			data: "{profiles: [prof1]}",
+			want: DirectoryMapping{Profiles: []string{"prof1"}},
+		},
+		{
+			name:    "Invalid",
+			data:    "prof1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got DirectoryMapping
+			err := yaml.Unmarshal([]byte(tt.data), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalYAML() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnmarshalYAML() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDirectoryMapping_MarshalYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping DirectoryMapping
+		want    string
+	}{
+		{
+			name:    "Short form",
+			mapping: DirectoryMapping{Profiles: []string{"prof1", "root"}},
+			want:    "- prof1\n- root\n",
+		},
+		{
+			name:    "Long form for exact mappings",
+			mapping: DirectoryMapping{Profiles: []string{"prof1"}, Exact: true},
+			want:    "profiles:\n- prof1\nexact: true\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := yaml.Marshal(tt.mapping)
+			if err != nil {
+				t.Fatalf("MarshalYAML() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("MarshalYAML() got = %q, want %q", string(got), tt.want)
+			}
+		})
+	}
+}
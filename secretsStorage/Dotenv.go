@@ -0,0 +1,257 @@
+package secretsStorage
+
+import (
+	"fmt"
+	"gopkg.in/errgo.v2/fmt/errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const DotenvTypeIdentifier = "dotenv"
+
+// DotenvTopLevelKey is the key of the variables defined before the first section of a dotenv file
+const DotenvTopLevelKey = "."
+
+// dotenvKeyPattern matches the allowed variable names in dotenv files
+var dotenvKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+func init() {
+	RegisterStorageAdapterType(DotenvTypeIdentifier, newDotenv, (&Dotenv{}).GetDefaultConfig())
+}
+
+// Dotenv reads .env files. If FilePath is a directory, the key of an entry is the path of a file in this directory. If
+// FilePath is a file, the key of an entry is the name of a section in this file. The variables before the first section
+// are shared by all sections.
+type Dotenv struct {
+	Name     string
+	FilePath string
+}
+
+// newDotenv creates a Dotenv storage adapter from its config
+func newDotenv(name string, config Storage) (StorageAdapter, error) {
+	return &Dotenv{
+		Name:     name,
+		FilePath: config.Config["path"],
+	}, nil
+}
+
+func (d *Dotenv) GetEntry(key string) (*Entry, error) {
+	fileInfo, err := os.Stat(d.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var variables map[string]string
+	if fileInfo.IsDir() {
+		variables, err = d.getFileVariables(key)
+	} else {
+		variables, err = d.getSectionVariables(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entry := NewEntry()
+	for name, value := range variables {
+		if err := entry.SetAttribute(name, value); err != nil {
+			return nil, err
+		}
+	}
+	return &entry, nil
+}
+
+// getFileVariables returns the top level variables of the file key in the directory FilePath
+func (d *Dotenv) getFileVariables(key string) (map[string]string, error) {
+	file := filepath.Join(d.FilePath, filepath.FromSlash(key))
+	relativePath, err := filepath.Rel(d.FilePath, file)
+	if err != nil || relativePath == "." || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return nil, errors.Newf("%s is not a file in %s", key, d.FilePath)
+	}
+	sections, err := parseDotenvFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return sections[""], nil
+}
+
+// getSectionVariables returns the variables of the section key in the file FilePath, including the top level variables
+func (d *Dotenv) getSectionVariables(key string) (map[string]string, error) {
+	sections, err := parseDotenvFile(d.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	if key == DotenvTopLevelKey {
+		return sections[""], nil
+	}
+	section, exists := sections[key]
+	if !exists || key == "" {
+		return nil, errors.Newf("Could not find section %s in %s", key, d.FilePath)
+	}
+	variables := map[string]string{}
+	for name, value := range sections[""] {
+		variables[name] = value
+	}
+	for name, value := range section {
+		variables[name] = value
+	}
+	return variables, nil
+}
+
+func (d *Dotenv) IsCaseSensitive() bool {
+	return true
+}
+
+func (d *Dotenv) Validate() (error, []string) {
+	fileInfo, err := os.Stat(d.FilePath)
+	pathExists := err == nil
+	isDirectory := pathExists && fileInfo.IsDir()
+	out := []string{fmt.Sprintf("Configured path is %s\nPath exists: %t\nPath is a directory: %t", d.FilePath, pathExists, isDirectory)}
+	if !pathExists {
+		return errors.Newf("Validation of %s failed. Run debug storage %s to check it in detail", d.Name, d.Name), out
+	}
+	return nil, out
+}
+
+func (d *Dotenv) GetDefaultConfig() map[string]string {
+	return map[string]string{
+		"path": "",
+	}
+}
+
+// parseDotenvFile parses the dotenv file at path, see parseDotenv
+func parseDotenvFile(path string) (map[string]map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sections, err := parseDotenv(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return sections, nil
+}
+
+// parseDotenv parses the content of a dotenv file into its sections. The variables before the first section are in
+// the section with the empty name. The following syntax is supported:
+//
+//	# comment
+//	KEY=unquoted value # inline comment
+//	export KEY=value
+//	KEY='literal value, may span lines'
+//	KEY="value with escapes like \n, may span lines"
+//	[section]
+//
+// Variables are not expanded.
+func parseDotenv(data string) (map[string]map[string]string, error) {
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	sections := map[string]map[string]string{"": {}}
+	section := ""
+	for lineIndex := 0; lineIndex < len(lines); lineIndex++ {
+		lineNumber := lineIndex + 1
+		trimmed := strings.TrimSpace(lines[lineIndex])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			if section == "" {
+				return nil, fmt.Errorf("line %d: section name must not be empty", lineNumber)
+			}
+			if _, exists := sections[section]; !exists {
+				sections[section] = map[string]string{}
+			}
+			continue
+		}
+
+		line := strings.TrimLeft(lines[lineIndex], " \t")
+		if rest, found := strings.CutPrefix(line, "export"); found && (strings.HasPrefix(rest, " ") || strings.HasPrefix(rest, "\t")) {
+			line = strings.TrimLeft(rest, " \t")
+		}
+		key, rawValue, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found {
+			return nil, fmt.Errorf("line %d: expected KEY=value", lineNumber)
+		}
+		if !dotenvKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid variable name %q", lineNumber, key)
+		}
+		rawValue = strings.TrimLeft(rawValue, " \t")
+
+		var value string
+		if strings.HasPrefix(rawValue, "'") || strings.HasPrefix(rawValue, `"`) {
+			var remainder string
+			var err error
+			value, remainder, lineIndex, err = parseDotenvQuotedValue(lines, lineIndex, rawValue)
+			if err != nil {
+				return nil, err
+			}
+			remainder = strings.TrimSpace(remainder)
+			if remainder != "" && !strings.HasPrefix(remainder, "#") {
+				return nil, fmt.Errorf("line %d: unexpected %q after the closing quote", lineIndex+1, remainder)
+			}
+		} else {
+			value = cutDotenvInlineComment(rawValue)
+		}
+		sections[section][key] = value
+	}
+	return sections, nil
+}
+
+// cutDotenvInlineComment removes a comment (a # after whitespace) from an unquoted value and trims the value
+func cutDotenvInlineComment(value string) string {
+	for i := 1; i < len(value); i++ {
+		if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+			value = value[:i]
+			break
+		}
+	}
+	if strings.HasPrefix(value, "#") {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
+
+// parseDotenvQuotedValue parses a quoted value starting in lines[lineIndex] with rawValue (which starts with the
+// quote). The value continues on the following lines until the closing quote. Returns the value, the rest of the line
+// after the closing quote and the index of the line containing the closing quote.
+func parseDotenvQuotedValue(lines []string, lineIndex int, rawValue string) (string, string, int, error) {
+	startLineNumber := lineIndex + 1
+	quote := rawValue[0]
+	current := rawValue[1:]
+	var value strings.Builder
+	for {
+		for i := 0; i < len(current); i++ {
+			char := current[i]
+			switch {
+			case char == quote:
+				return value.String(), current[i+1:], lineIndex, nil
+			case char == '\\' && quote == '"' && i+1 < len(current):
+				i++
+				switch current[i] {
+				case 'n':
+					value.WriteByte('\n')
+				case 'r':
+					value.WriteByte('\r')
+				case 't':
+					value.WriteByte('\t')
+				case '\\', '"', '$', '`':
+					value.WriteByte(current[i])
+				default:
+					// unknown escape sequences are kept as they are
+					value.WriteByte('\\')
+					value.WriteByte(current[i])
+				}
+			default:
+				value.WriteByte(char)
+			}
+		}
+		lineIndex++
+		if lineIndex >= len(lines) {
+			return "", "", lineIndex, fmt.Errorf("line %d: missing closing quote %c", startLineNumber, quote)
+		}
+		value.WriteByte('\n')
+		current = lines[lineIndex]
+	}
+}
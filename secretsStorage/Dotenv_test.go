@@ -0,0 +1,213 @@
+package secretsStorage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeDotenvFile writes content to name in dir and returns the path of the file
+func writeDotenvFile(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func Test_parseDotenv(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]map[string]string
+		wantErr bool
+	}{
+		{
+			name: "Unquoted values and comments",
+			data: "# comment\nKEY1=value1\n  KEY2 = value 2 # comment\nKEY3=a#b\nEMPTY=\nEMPTY2= # comment\n",
+			want: map[string]map[string]string{"": {"KEY1": "value1", "KEY2": "value 2", "KEY3": "a#b", "EMPTY": "", "EMPTY2": ""}},
+		},
+		{
+			name: "Export prefix",
+			data: "export KEY1=value1\nexport\tKEY2=value2\nexporter=value3",
+			want: map[string]map[string]string{"": {"KEY1": "value1", "KEY2": "value2", "exporter": "value3"}},
+		},
+		{
+			name: "Single quotes",
+			data: `KEY1='value # no comment \n' # comment`,
+			want: map[string]map[string]string{"": {"KEY1": `value # no comment \n`}},
+		},
+		{
+			name: "Double quotes with escapes",
+			data: `KEY1="line1\nline2\t\"quoted\" \$HOME \\ \x"`,
+			want: map[string]map[string]string{"": {"KEY1": "line1\nline2\t\"quoted\" $HOME \\ \\x"}},
+		},
+		{
+			name: "Multi-line values",
+			data: "KEY1=\"line1\n  line2\"\nKEY2='line1\r\nline2'\nKEY3=value3",
+			want: map[string]map[string]string{"": {"KEY1": "line1\n  line2", "KEY2": "line1\nline2", "KEY3": "value3"}},
+		},
+		{
+			name: "Sections",
+			data: "KEY1=value1\n[section1]\nKEY2=value2\n[ section2 ]\nKEY1=value3\n[section1]\nKEY3=value4",
+			want: map[string]map[string]string{
+				"":         {"KEY1": "value1"},
+				"section1": {"KEY2": "value2", "KEY3": "value4"},
+				"section2": {"KEY1": "value3"},
+			},
+		},
+		{
+			name:    "Missing closing quote",
+			data:    "KEY1=\"value1\nKEY2=value2",
+			wantErr: true,
+		},
+		{
+			name:    "Content after the closing quote",
+			data:    `KEY1="value1" value2`,
+			wantErr: true,
+		},
+		{
+			name:    "Missing equals sign",
+			data:    "KEY1",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid variable name",
+			data:    "1KEY=value1",
+			wantErr: true,
+		},
+		{
+			name:    "Empty section name",
+			data:    "[]",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDotenv(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseDotenv() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDotenv() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDotenv_GetEntry(t *testing.T) {
+	dir := t.TempDir()
+	sectionsFile := writeDotenvFile(t, dir, "sections.env", "SHARED=shared\nOVERRIDDEN=shared\n[section1]\nOVERRIDDEN=section1\n")
+	servicesDir := filepath.Join(dir, "services")
+	writeDotenvFile(t, servicesDir, "serviceA/.env", "KEY1=value1\n[ignored]\nKEY2=value2\n")
+	writeDotenvFile(t, dir, "outside.env", "KEY1=outside\n")
+
+	tests := []struct {
+		name     string
+		filePath string
+		key      string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "Section includes the top level variables",
+			filePath: sectionsFile,
+			key:      "section1",
+			want:     map[string]string{"SHARED": "shared", "OVERRIDDEN": "section1"},
+		},
+		{
+			name:     "Top level variables",
+			filePath: sectionsFile,
+			key:      DotenvTopLevelKey,
+			want:     map[string]string{"SHARED": "shared", "OVERRIDDEN": "shared"},
+		},
+		{
+			name:     "Unknown section",
+			filePath: sectionsFile,
+			key:      "unknown",
+			wantErr:  true,
+		},
+		{
+			name:     "File in directory",
+			filePath: servicesDir,
+			key:      "serviceA/.env",
+			want:     map[string]string{"KEY1": "value1"},
+		},
+		{
+			name:     "Missing file in directory",
+			filePath: servicesDir,
+			key:      "serviceB/.env",
+			wantErr:  true,
+		},
+		{
+			name:     "File outside of directory",
+			filePath: servicesDir,
+			key:      "../outside.env",
+			wantErr:  true,
+		},
+		{
+			name:     "Missing path",
+			filePath: filepath.Join(dir, "missing"),
+			key:      "section1",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Dotenv{Name: "dotenv", FilePath: tt.filePath}
+			got, err := d.GetEntry(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetEntry() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got.attributes, tt.want) {
+				t.Errorf("GetEntry() got = %v, want %v", got.attributes, tt.want)
+			}
+		})
+	}
+}
+
+func TestDotenv_Validate(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		filePath string
+		wantErr  bool
+	}{
+		{name: "Existing directory", filePath: dir},
+		{name: "Existing file", filePath: writeDotenvFile(t, dir, ".env", "KEY1=value1")},
+		{name: "Missing path", filePath: filepath.Join(dir, "missing"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Dotenv{Name: "dotenv", FilePath: tt.filePath}
+			err, out := d.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(out) != 1 {
+				t.Errorf("Validate() got %d messages, want 1", len(out))
+			}
+		})
+	}
+}
+
+func TestDotenv_GetDefaultConfig(t *testing.T) {
+	d := &Dotenv{}
+	want := map[string]string{
+		"path": "",
+	}
+	got := d.GetDefaultConfig()
+	if reflect.DeepEqual(want, got) == false {
+		t.Errorf("GetDefaultConfig() got = %v, want %v", got, want)
+	}
+}
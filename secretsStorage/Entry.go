@@ -10,6 +10,9 @@ import (
 // Entry is a storage independent representation of an entry
 type Entry struct {
 	attributes map[string]string
+	//attributeErrors hold the errors of attributes which could not be read, e.g. broken keepass field references. They
+	//are returned when the attribute is read, so the other attributes of the entry can still be used.
+	attributeErrors map[string]error
 }
 
 // NewEntry instantiates an Entry object
@@ -26,9 +29,20 @@ func (e *Entry) SetAttribute(key string, value string) error {
 		return errors.New("key must not be empty")
 	}
 	e.attributes[key] = value
+	delete(e.attributeErrors, key)
 	return nil
 }
 
+// setAttributeError sets an attribute which could not be read. GetAttribute returns err for it, value is the raw value
+// of the attribute.
+func (e *Entry) setAttributeError(key string, value string, err error) {
+	if e.attributeErrors == nil {
+		e.attributeErrors = map[string]error{}
+	}
+	e.attributes[key] = value
+	e.attributeErrors[key] = err
+}
+
 // GetAttribute retrieves an attribute from this entry. It will return an error
 // if the key is an empty string or does not exist.
 func (e *Entry) GetAttribute(key string) (*string, error) {
@@ -39,9 +53,21 @@ func (e *Entry) GetAttribute(key string) (*string, error) {
 	if exists == false {
 		return nil, errors.New(fmt.Sprintf("unknown attribute %s", key))
 	}
+	if err := e.attributeErrors[key]; err != nil {
+		return nil, err
+	}
 	return &value, nil
 }
 
+// readableAttributes returns the attributes without the ones which could not be read
+func (e *Entry) readableAttributes() map[string]string {
+	readable := maps.Clone(e.attributes)
+	for key := range e.attributeErrors {
+		delete(readable, key)
+	}
+	return readable
+}
+
 // GetAttributeNames returns a slice containing keys of the attributes of this entry.
 func (e *Entry) GetAttributeNames() []string {
 	keys := maps.Keys[map[string]string](e.attributes)
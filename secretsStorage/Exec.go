@@ -0,0 +1,318 @@
+package secretsStorage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/errgo.v2/fmt/errors"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const ExecTypeIdentifier = "exec"
+
+func init() {
+	RegisterStorageAdapterType(ExecTypeIdentifier, newExec, (&Exec{}).GetDefaultConfig())
+}
+
+// output formats of the command of an Exec storage adapter
+const (
+	//ExecFormatRaw uses the whole output as value of a single attribute
+	ExecFormatRaw = "raw"
+	//ExecFormatKeyValue reads KEY=value lines
+	ExecFormatKeyValue = "keyvalue"
+	//ExecFormatJSON reads a JSON object
+	ExecFormatJSON = "json"
+)
+
+// Exec runs an external command to retrieve an entry, e.g. the CLI of a password manager. Command is split into words
+// like a shell would do, then each word is rendered as text/template with the path of the entry as {{.Path}}. The
+// command is not run by a shell, so the path cannot inject further arguments.
+type Exec struct {
+	Name    string
+	Command string
+	//Format is the format of the output, see ExecFormatRaw (default), ExecFormatKeyValue and ExecFormatJSON
+	Format string
+	//Attribute is the name of the attribute containing the output with ExecFormatRaw, password by default
+	Attribute string
+	Timeout   time.Duration
+}
+
+// execTemplateData is passed to the templates of the command
+type execTemplateData struct {
+	Path string
+}
+
+// newExec creates an Exec storage adapter from its config
+func newExec(name string, config Storage) (StorageAdapter, error) {
+	timeout, err := parseExecTimeout(config.Config)
+	if err != nil {
+		return nil, err
+	}
+	return &Exec{
+		Name:      name,
+		Command:   config.Config["command"],
+		Format:    config.Config["format"],
+		Attribute: config.Config["attribute"],
+		Timeout:   timeout,
+	}, nil
+}
+
+func (e *Exec) GetEntry(key string) (*Entry, error) {
+	args, err := e.renderCommand(key)
+	if err != nil {
+		return nil, err
+	}
+	output, err := e.run(args)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := e.parseOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the output of %s: %w", args[0], err)
+	}
+
+	entry := NewEntry()
+	for name, value := range attributes {
+		if err := entry.SetAttribute(name, value); err != nil {
+			return nil, err
+		}
+	}
+	return &entry, nil
+}
+
+// renderCommand returns the arguments of the command for the entry path
+func (e *Exec) renderCommand(path string) ([]string, error) {
+	words, err := splitCommandTemplate(e.Command)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, errors.Newf("No command is configured for %s", e.Name)
+	}
+	args := make([]string, 0, len(words))
+	for _, word := range words {
+		wordTemplate, err := template.New("command").Option("missingkey=error").Parse(word)
+		if err != nil {
+			return nil, fmt.Errorf("invalid command template: %w", err)
+		}
+		var rendered strings.Builder
+		if err := wordTemplate.Execute(&rendered, execTemplateData{Path: path}); err != nil {
+			return nil, fmt.Errorf("invalid command template: %w", err)
+		}
+		args = append(args, rendered.String())
+	}
+	return args, nil
+}
+
+// run executes the command and returns its stdout. The output of stderr is part of the returned error.
+func (e *Exec) run(args []string) ([]byte, error) {
+	ctx := context.Background()
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	// the terminal stays connected to stdin, so the command can ask the user (e.g. to unlock the password manager)
+	cmd.Stdin = os.Stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	// stderr is captured as the wrapper would evaluate it
+	cmd.Stderr = &stderr
+	// do not wait for children of a killed command which still hold stdout open
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, errors.Newf("The command %s did not finish within %s", args[0], e.Timeout)
+	}
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			return nil, fmt.Errorf("the command %s failed: %w", args[0], err)
+		}
+		return nil, fmt.Errorf("the command %s failed: %w: %s", args[0], err, message)
+	}
+	return stdout.Bytes(), nil
+}
+
+// parseOutput converts the output of the command into attributes according to Format
+func (e *Exec) parseOutput(output []byte) (map[string]string, error) {
+	attributes := map[string]string{}
+	switch e.getFormat() {
+	case ExecFormatRaw:
+		value := strings.TrimSuffix(string(output), "\n")
+		attributes[e.getAttribute()] = strings.TrimSuffix(value, "\r")
+	case ExecFormatKeyValue:
+		scanner := bufio.NewScanner(bytes.NewReader(output))
+		lineNumber := 0
+		for scanner.Scan() {
+			lineNumber++
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, value, found := strings.Cut(line, "=")
+			if !found || strings.TrimSpace(key) == "" {
+				return nil, fmt.Errorf("line %d: expected KEY=value", lineNumber)
+			}
+			attributes[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	case ExecFormatJSON:
+		var values map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(output))
+		decoder.UseNumber()
+		if err := decoder.Decode(&values); err != nil {
+			return nil, err
+		}
+		for name, value := range values {
+			attributes[name] = formatJSONValue(value)
+		}
+	default:
+		return nil, errors.Newf("Unknown format %s", e.Format)
+	}
+	return attributes, nil
+}
+
+// getFormat returns the configured format or ExecFormatRaw
+func (e *Exec) getFormat() string {
+	if e.Format == "" {
+		return ExecFormatRaw
+	}
+	return e.Format
+}
+
+// getAttribute returns the configured attribute for the raw output or password
+func (e *Exec) getAttribute() string {
+	if e.Attribute == "" {
+		return "password"
+	}
+	return e.Attribute
+}
+
+func (e *Exec) IsCaseSensitive() bool {
+	return true
+}
+
+func (e *Exec) Validate() (error, []string) {
+	var out []string
+	validationFailed := false
+
+	executableFound := false
+	args, err := e.renderCommand("")
+	if err != nil {
+		validationFailed = true
+		out = append(out, fmt.Sprintf("Configured command is %s\nCommand is invalid: %s", e.Command, err))
+	} else {
+		_, err = exec.LookPath(args[0])
+		executableFound = err == nil
+		if !executableFound {
+			validationFailed = true
+		}
+		out = append(out, fmt.Sprintf("Configured command is %s\nExecutable %s found: %t", e.Command, args[0], executableFound))
+	}
+
+	switch e.getFormat() {
+	case ExecFormatRaw:
+		out = append(out, fmt.Sprintf("Configured format is %s\nAttribute is %s", ExecFormatRaw, e.getAttribute()))
+	case ExecFormatKeyValue, ExecFormatJSON:
+		out = append(out, fmt.Sprintf("Configured format is %s", e.Format))
+	default:
+		validationFailed = true
+		out = append(out, fmt.Sprintf("Configured format %s is unknown, use %s, %s or %s", e.Format, ExecFormatRaw, ExecFormatKeyValue, ExecFormatJSON))
+	}
+
+	if validationFailed {
+		return errors.Newf("Validation of %s failed. Run debug storage %s to check it in detail", e.Name, e.Name), out
+	}
+	return nil, out
+}
+
+func (e *Exec) GetDefaultConfig() map[string]string {
+	return map[string]string{
+		"command":   "",
+		"format":    ExecFormatRaw,
+		"attribute": "password",
+		"timeout":   "30s",
+	}
+}
+
+// parseExecTimeout parses the timeout option, an empty option means no timeout
+func parseExecTimeout(config map[string]string) (time.Duration, error) {
+	if config["timeout"] == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(config["timeout"])
+	if err != nil {
+		return 0, errors.Newf("Invalid value %s for option timeout, expected a duration like 30s", config["timeout"])
+	}
+	return timeout, nil
+}
+
+// splitCommandTemplate splits a command into words like a shell: words are separated by whitespace, single quotes
+// keep everything literally, double quotes and backslashes escape single characters. Template actions like
+// {{ .Path }} are kept as they are, even if they contain whitespace. Actions in single quotes are escaped, so they
+// are not rendered.
+func splitCommandTemplate(command string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(command); i++ {
+		char := command[i]
+		switch {
+		case strings.HasPrefix(command[i:], "{{"):
+			end := strings.Index(command[i:], "}}")
+			if end < 0 {
+				return nil, errors.New("Missing }} in command")
+			}
+			word.WriteString(command[i : i+end+2])
+			i += end + 1
+			inWord = true
+		case char == ' ' || char == '\t' || char == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case char == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("Missing closing ' in command")
+			}
+			word.WriteString(strings.ReplaceAll(command[i+1:i+1+end], "{{", `{{"{{"}}`))
+			i += end + 1
+			inWord = true
+		case char == '"':
+			i++
+			for ; i < len(command) && command[i] != '"'; i++ {
+				if command[i] == '\\' && i+1 < len(command) && strings.IndexByte(`"\$`, command[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(command[i])
+			}
+			if i >= len(command) {
+				return nil, errors.New(`Missing closing " in command`)
+			}
+			inWord = true
+		case char == '\\' && i+1 < len(command):
+			i++
+			word.WriteByte(command[i])
+			inWord = true
+		default:
+			word.WriteByte(char)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,158 @@
+package secretsStorage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_splitCommandTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{name: "Words", command: "op  read\top://{{.Path}}", want: []string{"op", "read", "op://{{.Path}}"}},
+		{name: "Template with whitespace", command: `bw get item {{ printf "%s x" .Path }}`, want: []string{"bw", "get", "item", `{{ printf "%s x" .Path }}`}},
+		{name: "Single quotes", command: `echo 'a "b" \c'`, want: []string{"echo", `a "b" \c`}},
+		{name: "Template in single quotes", command: `echo '{{.Path}}' "{{.Path}}"`, want: []string{"echo", `{{"{{"}}.Path}}`, "{{.Path}}"}},
+		{name: "Double quotes", command: `echo "a 'b' \"c\" \d"x`, want: []string{"echo", `a 'b' "c" \dx`}},
+		{name: "Backslash", command: `echo a\ b`, want: []string{"echo", "a b"}},
+		{name: "Empty quotes", command: `echo ''`, want: []string{"echo", ""}},
+		{name: "Empty", command: "  ", want: nil},
+		{name: "Missing single quote", command: "echo 'a", wantErr: true},
+		{name: "Missing double quote", command: `echo "a`, wantErr: true},
+		{name: "Missing template end", command: "echo {{.Path", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitCommandTemplate(tt.command)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("splitCommandTemplate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitCommandTemplate() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExec_GetEntry(t *testing.T) {
+	tests := []struct {
+		name        string
+		exec        *Exec
+		key         string
+		want        map[string]string
+		wantErrText string
+	}{
+		{
+			name: "Raw output",
+			exec: &Exec{Command: `printf 'secret-%s\n' {{.Path}}`, Format: ExecFormatRaw, Attribute: "password"},
+			key:  "entry1",
+			want: map[string]string{"password": "secret-entry1"},
+		},
+		{
+			name: "Raw output with default format and attribute",
+			exec: &Exec{Command: `printf 'line1\nline2'`},
+			want: map[string]string{"password": "line1\nline2"},
+		},
+		{
+			name: "Path is a single argument",
+			exec: &Exec{Command: `printf %s {{.Path}}`, Attribute: "path"},
+			key:  "a b; echo c",
+			want: map[string]string{"path": "a b; echo c"},
+		},
+		{
+			name: "Template in single quotes is not rendered",
+			exec: &Exec{Command: `printf '%s {{.Path}}' {{.Path}}`, Attribute: "path"},
+			key:  "entry1",
+			want: map[string]string{"path": "entry1 {{.Path}}"},
+		},
+		{
+			name: "Key value output",
+			exec: &Exec{Command: `printf '# comment\nUSER=user1\n\nPASS = a=b \n'`, Format: ExecFormatKeyValue},
+			want: map[string]string{"USER": "user1", "PASS": "a=b"},
+		},
+		{
+			name: "JSON output",
+			exec: &Exec{Command: `echo '{"user":"user1","port":5432,"nested":{"a":true},"empty":null}'`, Format: ExecFormatJSON},
+			want: map[string]string{"user": "user1", "port": "5432", "nested": `{"a":true}`, "empty": ""},
+		},
+		{
+			name:        "Invalid JSON output",
+			exec:        &Exec{Command: "echo no-json", Format: ExecFormatJSON},
+			wantErrText: "failed to parse the output",
+		},
+		{
+			name:        "Invalid key value output",
+			exec:        &Exec{Command: "echo no-key-value", Format: ExecFormatKeyValue},
+			wantErrText: "line 1",
+		},
+		{
+			name:        "Stderr is part of the error",
+			exec:        &Exec{Command: `sh -c 'echo not logged in >&2; exit 1'`},
+			wantErrText: "not logged in",
+		},
+		{
+			name:        "Timeout",
+			exec:        &Exec{Command: "sleep 5", Timeout: 100 * time.Millisecond},
+			wantErrText: "did not finish within 100ms",
+		},
+		{
+			name:        "Invalid template",
+			exec:        &Exec{Command: "echo {{.Unknown}}"},
+			wantErrText: "invalid command template",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.exec.Name = "exec"
+			got, err := tt.exec.GetEntry(tt.key)
+			if tt.wantErrText != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrText) {
+					t.Errorf("GetEntry() error = %v, want an error containing %q", err, tt.wantErrText)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetEntry() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.attributes, tt.want) {
+				t.Errorf("GetEntry() got = %q, want %q", got.attributes, tt.want)
+			}
+		})
+	}
+}
+
+func TestExec_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		exec    *Exec
+		wantErr bool
+	}{
+		{name: "Valid", exec: &Exec{Command: "printf %s {{.Path}}", Format: ExecFormatJSON}},
+		{name: "Default format", exec: &Exec{Command: "printf %s {{.Path}}"}},
+		{name: "Missing executable", exec: &Exec{Command: "envManager-nonexistent {{.Path}}"}, wantErr: true},
+		{name: "No command", exec: &Exec{}, wantErr: true},
+		{name: "Unknown format", exec: &Exec{Command: "printf %s {{.Path}}", Format: "xml"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.exec.Name = "exec"
+			err, out := tt.exec.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v, out %v", err, tt.wantErr, out)
+			}
+		})
+	}
+}
+
+func TestCreateStorageAdapter_execTimeout(t *testing.T) {
+	config := map[string]string{"command": "printf %s {{.Path}}", "timeout": "soon"}
+	if _, err := CreateStorageAdapter("exec", Storage{StorageType: ExecTypeIdentifier, Config: config}); err == nil {
+		t.Errorf("CreateStorageAdapter() with an invalid timeout did not fail")
+	}
+}
@@ -1,20 +1,43 @@
 package secretsStorage
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/base64"
 	"envManager/helper"
 	"fmt"
 	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/tobischo/gokeepasslib/v3/wrappers"
 	"gopkg.in/errgo.v2/fmt/errors"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 )
 
 const KeepassTypeIdentifier = "keepass"
 
+// KeepassAttachmentPrefix is the prefix of the attributes holding the attachments of an entry, e.g.
+// attachment:client.pem
+const KeepassAttachmentPrefix = "attachment:"
+
+func init() {
+	RegisterStorageAdapterType(KeepassTypeIdentifier, newKeepass, (&Keepass{}).GetDefaultConfig())
+}
+
 type Keepass struct {
 	Name     string
 	FilePath string
-	database *gokeepasslib.Database
+	//KeyFile is the path of the key file, if the database is secured with one
+	KeyFile string
+	//Passwordless is true if the database is secured with the key file only
+	Passwordless bool
+	//PasswordSources are checked for the password before prompting the user
+	PasswordSources helper.CredentialSources
+	database        *gokeepasslib.Database
 }
 
 func (k *Keepass) IsCaseSensitive() bool {
@@ -33,15 +56,52 @@ func (k *Keepass) Validate() (error, []string) {
 	}
 	out = append(out, fmt.Sprintf("Configured file is %s\nFile exists: %t", k.FilePath, fileExists))
 
+	if k.KeyFile != "" {
+		keyFileExists := true
+		_, err = os.Stat(k.KeyFile)
+		if err != nil {
+			keyFileExists = false
+			validationFailed = true
+		}
+		out = append(out, fmt.Sprintf("Configured key file is %s\nKey file exists: %t", k.KeyFile, keyFileExists))
+	} else if k.Passwordless {
+		validationFailed = true
+		out = append(out, "The database is passwordless but no key file is configured")
+	}
+
 	if validationFailed {
 		return errors.Newf("Validation of %s failed. Run debug storage %s to check it in detail", k.Name, k.Name), out
 	}
 	return nil, out
 }
 
-func (k *Keepass) promptCredentials() string {
-	password, _ := helper.GetInput().PromptPassword("Enter password for "+k.Name, '*')
-	return password
+// PromptSecret returns the password of the database from the configured sources
+// or asks the user for it
+func (k *Keepass) PromptSecret() (string, error) {
+	password, err := helper.GetInput().GetSecret(k.GetUnlockPrompt(), k.PasswordSources)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the password for %s: %w", k.Name, err)
+	}
+	return password, nil
+}
+
+// newKeepass creates a Keepass storage adapter from its config
+func newKeepass(name string, config Storage) (StorageAdapter, error) {
+	passwordless, err := parseBoolOption(config.Config, "passwordless")
+	if err != nil {
+		return nil, err
+	}
+	return &Keepass{
+		Name:         name,
+		FilePath:     config.Config["path"],
+		KeyFile:      config.Config["keyFile"],
+		Passwordless: passwordless,
+		PasswordSources: helper.CredentialSources{
+			Env:     config.Config["passwordEnv"],
+			File:    config.Config["passwordFile"],
+			Command: config.Config["passwordCommand"],
+		},
+	}, nil
 }
 
 func (k *Keepass) GetEntry(key string) (*Entry, error) {
@@ -67,23 +127,183 @@ func (k *Keepass) GetEntry(key string) (*Entry, error) {
 	if err != nil {
 		return nil, err
 	}
-	entry, err := toEntry(kpEntry)
+	entry, err := toEntry(k.database, kpEntry)
 	if err != nil {
 		return nil, err
 	}
 	return entry, nil
 }
 
+func (k *Keepass) ListEntries(prefix string) ([]string, error) {
+	if k.database == nil {
+		err := k.openDatabase()
+		if err != nil {
+			return nil, err
+		}
+	}
+	var paths []string
+	collectEntryPaths(&k.database.Content.Root.Groups[0], "", prefix, &paths)
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// SetEntry sets the attributes of entry on the entry with the title of the last part of key, creating the groups and
+// the entry as needed. The previous version of an existing entry is kept in its history. The database is read again
+// before it is changed, so changes made since it was opened are not lost.
+func (k *Keepass) SetEntry(key string, entry *Entry) error {
+	for _, name := range entry.GetAttributeNames() {
+		if strings.HasPrefix(name, KeepassAttachmentPrefix) {
+			return errors.Newf("%s is an attachment, attachments cannot be set", name)
+		}
+	}
+	if k.database == nil {
+		err := k.openDatabase()
+		if err != nil {
+			return err
+		}
+	}
+	database, err := k.decodeDatabase(k.database.Credentials)
+	if err != nil {
+		return err
+	}
+	parts := strings.Split(key, "/")
+	lastIndex := len(parts) - 1
+	if parts[lastIndex] == "" {
+		return errors.Newf("%s is not a valid entry path", key)
+	}
+	currentGroup := &database.Content.Root.Groups[0]
+	for _, part := range parts[:lastIndex] {
+		currentGroup = getOrCreateGroup(currentGroup, part)
+	}
+
+	kpEntry := findEntryPointer(currentGroup, parts[lastIndex])
+	if kpEntry == nil {
+		currentGroup.Entries = append(currentGroup.Entries, gokeepasslib.NewEntry())
+		kpEntry = &currentGroup.Entries[len(currentGroup.Entries)-1]
+		kpEntry.Values = append(kpEntry.Values, gokeepasslib.ValueData{Key: "Title", Value: gokeepasslib.V{Content: parts[lastIndex]}})
+	} else {
+		previous := *kpEntry
+		previous.Histories = nil
+		if len(kpEntry.Histories) == 0 {
+			kpEntry.Histories = append(kpEntry.Histories, gokeepasslib.History{})
+		}
+		kpEntry.Histories[0].Entries = append(kpEntry.Histories[0].Entries, previous)
+		// the values must not be shared with the history entry
+		kpEntry.Values = slices.Clone(kpEntry.Values)
+	}
+	for _, name := range entry.GetAttributeNames() {
+		value, _ := entry.GetAttribute(name)
+		if valueData := kpEntry.Get(name); valueData != nil {
+			valueData.Value.Content = *value
+		} else {
+			kpEntry.Values = append(kpEntry.Values, gokeepasslib.ValueData{
+				Key:   name,
+				Value: gokeepasslib.V{Content: *value, Protected: wrappers.NewBoolWrapper(name == "Password")},
+			})
+		}
+	}
+	now := wrappers.Now()
+	kpEntry.Times.LastModificationTime = &now
+
+	if err := k.encodeDatabase(database); err != nil {
+		return err
+	}
+	k.database = database
+	return nil
+}
+
+// encodeDatabase writes the database to FilePath. It is written to a temporary file first, so the database is not
+// corrupted if writing fails.
+func (k *Keepass) encodeDatabase(database *gokeepasslib.Database) error {
+	fileInfo, err := os.Stat(k.FilePath)
+	if err != nil {
+		return err
+	}
+	if err := renewDatabaseSeeds(database); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(k.FilePath), filepath.Base(k.FilePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer os.Remove(file.Name())
+	if err := database.LockProtectedEntries(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	err = gokeepasslib.NewEncoder(file).Encode(database)
+	if unlockErr := database.UnlockProtectedEntries(); err == nil {
+		err = unlockErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", k.FilePath, err)
+	}
+	if err := os.Chmod(file.Name(), fileInfo.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), k.FilePath)
+}
+
+// renewDatabaseSeeds replaces the master seed, the encryption IV and the key of the inner random stream with random
+// bytes, as KeePass does on every save. The decoded values must not be reused, otherwise every version of the file is
+// encrypted with the same key stream. The database must be unlocked, the protected values are locked with the new key.
+func renewDatabaseSeeds(database *gokeepasslib.Database) error {
+	fileHeaders := database.Header.FileHeaders
+	seeds := []*[]byte{&fileHeaders.MasterSeed, &fileHeaders.EncryptionIV}
+	if database.Header.IsKdbx4() {
+		seeds = append(seeds, &database.Content.InnerHeader.InnerRandomStreamKey)
+	} else {
+		seeds = append(seeds, &fileHeaders.ProtectedStreamKey, &fileHeaders.StreamStartBytes)
+	}
+	for _, seed := range seeds {
+		renewed := make([]byte, len(*seed))
+		if _, err := rand.Read(renewed); err != nil {
+			return err
+		}
+		*seed = renewed
+	}
+	return nil
+}
+
 func (k *Keepass) GetDefaultConfig() map[string]string {
 	return map[string]string{
-		"path": "",
+		"path":            "",
+		"keyFile":         "",
+		"passwordless":    "false",
+		"passwordEnv":     "",
+		"passwordFile":    "",
+		"passwordCommand": "",
 	}
 }
 
-func toEntry(kpEntry *gokeepasslib.Entry) (*Entry, error) {
+// toEntry converts kpEntry to an Entry. Field references like {REF:P@I:<uuid>} in the values are resolved, an attribute
+// with a reference which cannot be resolved returns the error when it is read. The attachments are added as attributes
+// named KeepassAttachmentPrefix and the name of the attachment.
+func toEntry(database *gokeepasslib.Database, kpEntry *gokeepasslib.Entry) (*Entry, error) {
 	entry := NewEntry()
 	for _, valueData := range kpEntry.Values {
-		err := entry.SetAttribute(valueData.Key, valueData.Value.Content)
+		value, err := resolveKeepassReferences(database, valueData.Value.Content, 0)
+		if err != nil {
+			// only fail if the attribute is used, the other attributes of the entry can still be read
+			err = fmt.Errorf("failed to read %s of %s: %w", valueData.Key, kpEntry.GetTitle(), err)
+			entry.setAttributeError(valueData.Key, valueData.Value.Content, err)
+			continue
+		}
+		err = entry.SetAttribute(valueData.Key, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, reference := range kpEntry.Binaries {
+		content, err := getBinaryContent(database, reference.Value.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the attachment %s of %s: %w", reference.Name, kpEntry.GetTitle(), err)
+		}
+		err = entry.SetAttribute(KeepassAttachmentPrefix+reference.Name, string(content))
 		if err != nil {
 			return nil, err
 		}
@@ -91,6 +311,61 @@ func toEntry(kpEntry *gokeepasslib.Entry) (*Entry, error) {
 	return &entry, nil
 }
 
+// getBinaryContent returns the content of the binary with the id from the binary pool of the database. KDBX 4 keeps the
+// binaries as they are in the inner header, KDBX 3 keeps them base64 encoded and optionally gzipped in the meta data.
+func getBinaryContent(database *gokeepasslib.Database, id int) ([]byte, error) {
+	if database.Header != nil && database.Header.IsKdbx4() {
+		if database.Content.InnerHeader == nil {
+			return nil, errors.Newf("binary %d does not exist", id)
+		}
+		binary := database.Content.InnerHeader.Binaries.Find(id)
+		if binary == nil {
+			return nil, errors.Newf("binary %d does not exist", id)
+		}
+		return binary.Content, nil
+	}
+
+	if database.Content.Meta == nil {
+		return nil, errors.Newf("binary %d does not exist", id)
+	}
+	binary := database.Content.Meta.Binaries.Find(id)
+	if binary == nil {
+		return nil, errors.Newf("binary %d does not exist", id)
+	}
+	content, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(binary.Content)))
+	if err != nil {
+		return nil, err
+	}
+	if !binary.Compressed.Bool {
+		return content, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// collectEntryPaths adds the paths of all entries in group and its subgroups starting with prefix to paths. The path of
+// group is groupPath.
+func collectEntryPaths(group *gokeepasslib.Group, groupPath string, prefix string, paths *[]string) {
+	for _, entry := range group.Entries {
+		path := groupPath + entry.GetTitle()
+		if strings.HasPrefix(path, prefix) {
+			*paths = append(*paths, path)
+		}
+	}
+	for i := range group.Groups {
+		subGroupPath := groupPath + group.Groups[i].Name + "/"
+		// skip groups which cannot contain matching entries
+		if strings.HasPrefix(subGroupPath, prefix) || strings.HasPrefix(prefix, subGroupPath) {
+			collectEntryPaths(&group.Groups[i], subGroupPath, prefix, paths)
+		}
+	}
+}
+
 func findEntry(group *gokeepasslib.Group, name string) (*gokeepasslib.Entry, error) {
 	for _, entry := range group.Entries {
 		if entry.GetTitle() == name {
@@ -100,6 +375,31 @@ func findEntry(group *gokeepasslib.Group, name string) (*gokeepasslib.Entry, err
 	return nil, errors.New(fmt.Sprintf("Could not find entry with name %s in group %s", name, group.Name))
 }
 
+// findEntryPointer returns the entry with the title name in group, so it can be changed. It returns nil if there is no
+// such entry.
+func findEntryPointer(group *gokeepasslib.Group, name string) *gokeepasslib.Entry {
+	for i := range group.Entries {
+		if group.Entries[i].GetTitle() == name {
+			return &group.Entries[i]
+		}
+	}
+	return nil
+}
+
+// getOrCreateGroup returns the subgroup with the name in group, so it can be changed. The subgroup is created if it
+// does not exist.
+func getOrCreateGroup(group *gokeepasslib.Group, name string) *gokeepasslib.Group {
+	for i := range group.Groups {
+		if group.Groups[i].Name == name {
+			return &group.Groups[i]
+		}
+	}
+	subGroup := gokeepasslib.NewGroup()
+	subGroup.Name = name
+	group.Groups = append(group.Groups, subGroup)
+	return &group.Groups[len(group.Groups)-1]
+}
+
 func findGroup(group *gokeepasslib.Group, name string) (*gokeepasslib.Group, error) {
 	for _, subGroup := range group.Groups {
 		if subGroup.Name == name {
@@ -110,19 +410,79 @@ func findGroup(group *gokeepasslib.Group, name string) (*gokeepasslib.Group, err
 }
 
 func (k *Keepass) openDatabase() error {
-	fileHandle, err := os.Open(k.FilePath)
+	if k.Passwordless {
+		return k.Unlock("")
+	}
+	password, err := k.PromptSecret()
 	if err != nil {
 		return err
 	}
-	k.database = gokeepasslib.NewDatabase()
-	k.database.Credentials = gokeepasslib.NewPasswordCredentials(k.promptCredentials())
-	err = gokeepasslib.NewDecoder(fileHandle).Decode(k.database)
+	return k.Unlock(password)
+}
+
+// IsLocked checks if the database still needs to be opened
+func (k *Keepass) IsLocked() bool {
+	return k.database == nil
+}
+
+// GetUnlockPrompt returns the prompt asking for the password of the database. A
+// passwordless database needs no prompt.
+func (k *Keepass) GetUnlockPrompt() string {
+	if k.Passwordless {
+		return ""
+	}
+	return "Enter password for " + k.Name
+}
+
+// Unlock opens the database with the given password and the key file, if one is
+// configured. The password is ignored for passwordless databases.
+func (k *Keepass) Unlock(secret string) error {
+	credentials, err := k.createCredentials(secret)
 	if err != nil {
 		return err
 	}
-	err = k.database.UnlockProtectedEntries()
+	database, err := k.decodeDatabase(credentials)
 	if err != nil {
 		return err
 	}
+	k.database = database
 	return nil
 }
+
+// decodeDatabase reads the database from FilePath with the credentials
+func (k *Keepass) decodeDatabase(credentials *gokeepasslib.DBCredentials) (*gokeepasslib.Database, error) {
+	fileHandle, err := os.Open(k.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer fileHandle.Close()
+	database := gokeepasslib.NewDatabase()
+	database.Credentials = credentials
+	err = gokeepasslib.NewDecoder(fileHandle).Decode(database)
+	if err != nil {
+		return nil, err
+	}
+	err = database.UnlockProtectedEntries()
+	if err != nil {
+		return nil, err
+	}
+	return database, nil
+}
+
+// createCredentials combines the password and the key file to the credentials of the database
+func (k *Keepass) createCredentials(password string) (*gokeepasslib.DBCredentials, error) {
+	switch {
+	case k.KeyFile == "":
+		return gokeepasslib.NewPasswordCredentials(password), nil
+	case k.Passwordless:
+		return gokeepasslib.NewKeyCredentials(k.KeyFile)
+	default:
+		return gokeepasslib.NewPasswordAndKeyCredentials(password, k.KeyFile)
+	}
+}
+
+// Lock closes the database, the password is required again to open it
+func (k *Keepass) Lock() {
+	k.database = nil
+}
@@ -0,0 +1,124 @@
+package secretsStorage
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/tobischo/gokeepasslib/v3"
+	"gopkg.in/errgo.v2/fmt/errors"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// keepassReferencePattern matches field references like {REF:P@I:<uuid>}, which take the password of the entry with
+// the uuid. See https://keepass.info/help/base/fieldrefs.html
+var keepassReferencePattern = regexp.MustCompile(`(?i)\{REF:([^@}]*)@([^:}]*):([^}]*)}`)
+
+// maxKeepassReferenceDepth limits how deep references may be nested, so references to each other end with an error
+const maxKeepassReferenceDepth = 10
+
+// keepassReferenceFields maps the field codes of references to the keys of the values of an entry. I (the uuid) and O
+// (other fields, only for searching) are handled separately.
+var keepassReferenceFields = map[string]string{
+	"T": "Title",
+	"U": "UserName",
+	"P": "Password",
+	"A": "URL",
+	"N": "Notes",
+}
+
+// resolveKeepassReferences replaces the field references in value with the fields of the referenced entries. References
+// in the referenced fields are resolved as well, up to maxKeepassReferenceDepth levels.
+func resolveKeepassReferences(database *gokeepasslib.Database, value string, depth int) (string, error) {
+	if !strings.Contains(strings.ToUpper(value), "{REF:") {
+		return value, nil
+	}
+	if depth >= maxKeepassReferenceDepth {
+		return "", errors.Newf(
+			"references are nested deeper than %d levels, they probably reference each other",
+			maxKeepassReferenceDepth,
+		)
+	}
+	var resolveErr error
+	resolved := keepassReferencePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		if resolveErr != nil {
+			return ""
+		}
+		parts := keepassReferencePattern.FindStringSubmatch(reference)
+		field, err := resolveKeepassReference(database, strings.ToUpper(parts[1]), strings.ToUpper(parts[2]), parts[3])
+		if err != nil {
+			resolveErr = fmt.Errorf("failed to resolve %s: %w", reference, err)
+			return ""
+		}
+		field, resolveErr = resolveKeepassReferences(database, field, depth+1)
+		return field
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+	return resolved, nil
+}
+
+// resolveKeepassReference returns the field wantedField of the first entry whose field searchIn matches text
+func resolveKeepassReference(database *gokeepasslib.Database, wantedField string, searchIn string, text string) (string, error) {
+	if _, isKnown := keepassReferenceFields[wantedField]; !isKnown && wantedField != "I" {
+		return "", errors.Newf("unknown field %s, use one of T, U, P, A, N or I", wantedField)
+	}
+	if _, isKnown := keepassReferenceFields[searchIn]; !isKnown && searchIn != "I" && searchIn != "O" {
+		return "", errors.Newf("unknown search field %s, use one of T, U, P, A, N, I or O", searchIn)
+	}
+	var kpEntry *gokeepasslib.Entry
+	for i := range database.Content.Root.Groups {
+		if kpEntry = findReferencedEntry(&database.Content.Root.Groups[i], searchIn, text); kpEntry != nil {
+			break
+		}
+	}
+	if kpEntry == nil {
+		return "", errors.New("the referenced entry does not exist")
+	}
+	if wantedField == "I" {
+		return strings.ToUpper(hex.EncodeToString(kpEntry.UUID[:])), nil
+	}
+	return kpEntry.GetContent(keepassReferenceFields[wantedField]), nil
+}
+
+// findReferencedEntry returns the first entry in group or its subgroups whose field searchIn matches text. Texts are
+// compared case-insensitive like KeePass does.
+func findReferencedEntry(group *gokeepasslib.Group, searchIn string, text string) *gokeepasslib.Entry {
+	for i := range group.Entries {
+		if keepassReferenceMatches(&group.Entries[i], searchIn, text) {
+			return &group.Entries[i]
+		}
+	}
+	for i := range group.Groups {
+		if kpEntry := findReferencedEntry(&group.Groups[i], searchIn, text); kpEntry != nil {
+			return kpEntry
+		}
+	}
+	return nil
+}
+
+// keepassReferenceMatches checks if the field searchIn of kpEntry matches text
+func keepassReferenceMatches(kpEntry *gokeepasslib.Entry, searchIn string, text string) bool {
+	switch searchIn {
+	case "I":
+		return strings.EqualFold(hex.EncodeToString(kpEntry.UUID[:]), strings.ReplaceAll(text, "-", ""))
+	case "O":
+		return slices.ContainsFunc(kpEntry.Values, func(valueData gokeepasslib.ValueData) bool {
+			_, isStandard := keepassReferenceFields[keepassFieldCode(valueData.Key)]
+			return !isStandard && strings.EqualFold(valueData.Value.Content, text)
+		})
+	default:
+		return strings.EqualFold(kpEntry.GetContent(keepassReferenceFields[searchIn]), text)
+	}
+}
+
+// keepassFieldCode returns the field code of a standard field or an empty string for other fields
+func keepassFieldCode(key string) string {
+	for code, field := range keepassReferenceFields {
+		if field == key {
+			return code
+		}
+	}
+	return ""
+}
@@ -0,0 +1,174 @@
+package secretsStorage
+
+import (
+	"encoding/hex"
+	"envManager/environment"
+	"envManager/helper"
+	"github.com/stretchr/testify/assert"
+	"github.com/tobischo/gokeepasslib/v3"
+	"strings"
+	"testing"
+)
+
+func TestKeepass_GetEntry_references(t *testing.T) {
+	shared := newTestEntry(map[string]string{
+		"Title":    "shared",
+		"UserName": "shared-user",
+		"Password": "shared-pass",
+		"URL":      "https://db.example.com",
+		"Notes":    "shared notes",
+		"Team":     "platform",
+	})
+	uuid := hex.EncodeToString(shared.UUID[:])
+
+	var entries []gokeepasslib.Entry
+	addEntry := func(values map[string]string) {
+		entries = append(entries, newTestEntry(values))
+	}
+	addEntry(map[string]string{"Title": "by-uuid", "UserName": "{REF:U@I:" + uuid + "}", "Password": "{REF:P@I:" + strings.ToUpper(uuid) + "}"})
+	addEntry(map[string]string{"Title": "by-title", "Password": "{ref:p@t:Shared}", "URL": "{REF:A@T:shared}", "Notes": "{REF:N@T:shared}"})
+	addEntry(map[string]string{"Title": "embedded", "URL": "postgres://{REF:U@T:shared}:{REF:P@T:shared}@db"})
+	addEntry(map[string]string{"Title": "by-other-field", "UserName": "{REF:U@O:platform}", "Notes": "{REF:I@T:shared}"})
+	addEntry(map[string]string{"Title": "nested", "Password": "{REF:P@T:by-title}"})
+	addEntry(map[string]string{"Title": "dangling", "Password": "{REF:P@I:00000000000000000000000000000000}"})
+	addEntry(map[string]string{"Title": "unknown-field", "Password": "{REF:X@T:shared}"})
+	addEntry(map[string]string{"Title": "cycle-a", "Password": "{REF:P@T:cycle-b}"})
+	addEntry(map[string]string{"Title": "cycle-b", "Password": "{REF:P@T:cycle-a}"})
+	addEntry(map[string]string{"Title": "broken-notes", "Password": "own-pass", "Notes": "see {REF:N@I:00000000000000000000000000000000}"})
+
+	databaseFile := writeTestDatabase(t, gokeepasslib.WithDatabaseKDBXVersion4(), func(database *gokeepasslib.Database) {
+		root := &database.Content.Root.Groups[0]
+		group := gokeepasslib.NewGroup()
+		group.Name = "group"
+		group.Entries = append(group.Entries, shared)
+		root.Groups = append(root.Groups, group)
+		root.Entries = append(root.Entries, entries...)
+	})
+	k := &Keepass{Name: "references", FilePath: databaseFile}
+	helper.GetInput().Inputs = []string{"1234"}
+	if err := k.openDatabase(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		want map[string]string
+		//wantErrAttribute is the attribute which returns an error containing wantErrText
+		wantErrAttribute string
+		wantErrText      string
+	}{
+		{
+			name: "By uuid",
+			key:  "by-uuid",
+			want: map[string]string{"UserName": "shared-user", "Password": "shared-pass"},
+		},
+		{
+			name: "By title",
+			key:  "by-title",
+			want: map[string]string{"Password": "shared-pass", "URL": "https://db.example.com", "Notes": "shared notes"},
+		},
+		{
+			name: "Embedded in text",
+			key:  "embedded",
+			want: map[string]string{"URL": "postgres://shared-user:shared-pass@db"},
+		},
+		{
+			name: "By other field and uuid as wanted field",
+			key:  "by-other-field",
+			want: map[string]string{"UserName": "shared-user", "Notes": strings.ToUpper(uuid)},
+		},
+		{
+			name: "Nested references",
+			key:  "nested",
+			want: map[string]string{"Password": "shared-pass"},
+		},
+		{
+			name:             "Dangling reference",
+			key:              "dangling",
+			wantErrAttribute: "Password",
+			wantErrText:      "the referenced entry does not exist",
+		},
+		{
+			name:             "Unknown field",
+			key:              "unknown-field",
+			wantErrAttribute: "Password",
+			wantErrText:      "unknown field X",
+		},
+		{
+			name:             "Cycle",
+			key:              "cycle-a",
+			wantErrAttribute: "Password",
+			wantErrText:      "nested deeper than 10 levels",
+		},
+		{
+			name:             "Broken reference in another attribute",
+			key:              "broken-notes",
+			want:             map[string]string{"Password": "own-pass"},
+			wantErrAttribute: "Notes",
+			wantErrText:      "failed to read Notes of broken-notes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := k.GetEntry(tt.key)
+			if err != nil {
+				t.Fatalf("GetEntry() returned error %v", err)
+			}
+			for name, want := range tt.want {
+				value, err := got.GetAttribute(name)
+				if assert.NoError(t, err, "GetAttribute(%s)", name) {
+					assert.Equalf(t, want, *value, "GetAttribute(%s)", name)
+				}
+			}
+			if tt.wantErrAttribute != "" {
+				_, err := got.GetAttribute(tt.wantErrAttribute)
+				if assert.Error(t, err, "GetAttribute(%s)", tt.wantErrAttribute) {
+					assert.Contains(t, err.Error(), tt.wantErrText, "GetAttribute(%s)", tt.wantErrAttribute)
+				}
+			}
+		})
+	}
+}
+
+func TestProfile_AddToEnvironment_brokenReference(t *testing.T) {
+	databaseFile := writeTestDatabase(t, gokeepasslib.WithDatabaseKDBXVersion4(), func(database *gokeepasslib.Database) {
+		entry := newTestEntry(map[string]string{"Title": "service", "Password": "pass", "Notes": "{REF:N@T:missing}", "URL": "{REF:A@T:missing}"})
+		root := &database.Content.Root.Groups[0]
+		root.Entries = append(root.Entries, entry)
+	})
+	_ = GetRegistry().AddStorage("brokenReference", &Keepass{FilePath: databaseFile})
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantErrText string
+	}{
+		{name: "Attribute without reference", env: map[string]string{"PASS": "Password"}},
+		{name: "Template without broken attribute", env: map[string]string{"PASS": "pw={{.Password}}"}},
+		{name: "Attribute with broken reference", env: map[string]string{"NOTES": "Notes"}, wantErrText: "failed to read Notes"},
+		{name: "Template with broken attribute", env: map[string]string{"NOTES": "{{.Notes}}"}, wantErrText: "failed to read Notes"},
+		{name: "Template with broken attribute as variable", env: map[string]string{"NOTES": "{{$.Notes}}"}, wantErrText: "failed to read Notes"},
+		{name: "Template with broken attribute in condition", env: map[string]string{"NOTES": "{{if .Notes}}x{{end}}"}, wantErrText: "failed to read Notes"},
+		{
+			name:        "Template with missing attribute named like a broken one",
+			env:         map[string]string{"URLS": "{{.URLs}}"},
+			wantErrText: `map has no entry for key "URLs"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Profile{name: "brokenReference", Storage: "brokenReference", Path: "service", Env: tt.env}
+			env := environment.NewEnvironment()
+			helper.GetInput().Inputs = []string{"1234"}
+			err := p.AddToEnvironment(&env)
+			if tt.wantErrText == "" {
+				assert.NoError(t, err, "AddToEnvironment()")
+				return
+			}
+			if assert.Error(t, err, "AddToEnvironment()") {
+				assert.Contains(t, err.Error(), tt.wantErrText, "AddToEnvironment()")
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package secretsStorage
 
 import (
+	"envManager/helper"
 	"envManager/internal"
 	"github.com/stretchr/testify/assert"
 	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/tobischo/gokeepasslib/v3/wrappers"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -16,9 +20,11 @@ func TestKeepass_Validate(t *testing.T) {
 	existingFile := internal.GetTestDataFile(t, "keepass.kdbx")
 	notExistingFile := internal.GetTestDataFile(t, "missing.kdbx")
 	type fields struct {
-		Name     string
-		FilePath string
-		database *gokeepasslib.Database
+		Name         string
+		FilePath     string
+		KeyFile      string
+		Passwordless bool
+		database     *gokeepasslib.Database
 	}
 	tests := []struct {
 		name             string
@@ -38,6 +44,45 @@ func TestKeepass_Validate(t *testing.T) {
 			wantErrorMessage: "",
 			wantMessage:      []string{"Configured file is " + existingFile + "\nFile exists: true"},
 		},
+		{
+			name: "Existing key file",
+			fields: fields{
+				Name:     "test_keepass",
+				FilePath: existingFile,
+				KeyFile:  existingFile,
+			},
+			wantError: false,
+			wantMessage: []string{
+				"Configured file is " + existingFile + "\nFile exists: true",
+				"Configured key file is " + existingFile + "\nKey file exists: true",
+			},
+		},
+		{
+			name: "Missing key file",
+			fields: fields{
+				Name:     "test_keepass",
+				FilePath: existingFile,
+				KeyFile:  notExistingFile,
+			},
+			wantError: true,
+			wantMessage: []string{
+				"Configured file is " + existingFile + "\nFile exists: true",
+				"Configured key file is " + notExistingFile + "\nKey file exists: false",
+			},
+		},
+		{
+			name: "Passwordless without key file",
+			fields: fields{
+				Name:         "test_keepass",
+				FilePath:     existingFile,
+				Passwordless: true,
+			},
+			wantError: true,
+			wantMessage: []string{
+				"Configured file is " + existingFile + "\nFile exists: true",
+				"The database is passwordless but no key file is configured",
+			},
+		},
 		{
 			name: "Missing file",
 			fields: fields{
@@ -53,9 +98,11 @@ func TestKeepass_Validate(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			k := Keepass{
-				Name:     tt.fields.Name,
-				FilePath: tt.fields.FilePath,
-				database: tt.fields.database,
+				Name:         tt.fields.Name,
+				FilePath:     tt.fields.FilePath,
+				KeyFile:      tt.fields.KeyFile,
+				Passwordless: tt.fields.Passwordless,
+				database:     tt.fields.database,
 			}
 			gotErr, gotMessages := k.Validate()
 
@@ -69,3 +116,361 @@ func TestKeepass_Validate(t *testing.T) {
 		})
 	}
 }
+
+// createKeepassWithKeyFile writes a database secured with a key file (and the password, if it is not empty) to a
+// temporary directory. The database contains the entry "entry1" with the UserName "user1".
+func createKeepassWithKeyFile(t *testing.T, password string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "database.key")
+	if err := os.WriteFile(keyFile, []byte("some random key file content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	database := gokeepasslib.NewDatabase()
+	var err error
+	if password == "" {
+		database.Credentials, err = gokeepasslib.NewKeyCredentials(keyFile)
+	} else {
+		database.Credentials, err = gokeepasslib.NewPasswordAndKeyCredentials(password, keyFile)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := gokeepasslib.NewEntry()
+	entry.Values = append(
+		entry.Values,
+		gokeepasslib.ValueData{Key: "Title", Value: gokeepasslib.V{Content: "entry1"}},
+		gokeepasslib.ValueData{Key: "UserName", Value: gokeepasslib.V{Content: "user1"}},
+	)
+	database.Content.Root.Groups[0].Entries = append(database.Content.Root.Groups[0].Entries, entry)
+	if err := database.LockProtectedEntries(); err != nil {
+		t.Fatal(err)
+	}
+
+	databaseFile := filepath.Join(dir, "database.kdbx")
+	file, err := os.Create(databaseFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer file.Close()
+	if err := gokeepasslib.NewEncoder(file).Encode(database); err != nil {
+		t.Fatal(err)
+	}
+	return databaseFile, keyFile
+}
+
+func TestKeepass_GetEntry_keyFile(t *testing.T) {
+	t.Run("Password and key file", func(t *testing.T) {
+		databaseFile, keyFile := createKeepassWithKeyFile(t, "1234")
+		storage, err := CreateStorageAdapter("keyFile", Storage{
+			StorageType: KeepassTypeIdentifier,
+			Config:      map[string]string{"path": databaseFile, "keyFile": keyFile},
+		})
+		if err != nil {
+			t.Fatalf("CreateStorageAdapter() returned error %v", err)
+		}
+		helper.GetInput().Inputs = []string{"1234"}
+		assertEntryAttribute(t, storage, "entry1", "UserName", "user1")
+	})
+
+	t.Run("Password without key file", func(t *testing.T) {
+		databaseFile, _ := createKeepassWithKeyFile(t, "1234")
+		k := Keepass{Name: "keyFile", FilePath: databaseFile}
+		if err := k.Unlock("1234"); err == nil {
+			t.Error("Unlock() without key file got no error but wanted one")
+		}
+	})
+
+	t.Run("Key file only", func(t *testing.T) {
+		databaseFile, keyFile := createKeepassWithKeyFile(t, "")
+		storage, err := CreateStorageAdapter("keyFile", Storage{
+			StorageType: KeepassTypeIdentifier,
+			Config:      map[string]string{"path": databaseFile, "keyFile": keyFile, "passwordless": "true"},
+		})
+		if err != nil {
+			t.Fatalf("CreateStorageAdapter() returned error %v", err)
+		}
+		// no preset input, a prompt would fail
+		helper.GetInput().Inputs = nil
+		assertEntryAttribute(t, storage, "entry1", "UserName", "user1")
+	})
+}
+
+func TestKeepass_GetEntry_unsetPasswordEnv(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte("1234\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		config map[string]string
+		inputs []string
+	}{
+		{
+			name:   "Falls back to the password file",
+			config: map[string]string{"passwordEnv": "ENVMANAGER_TEST_UNSET_PASSWORD", "passwordFile": passwordFile},
+		},
+		{
+			name:   "Falls back to the prompt",
+			config: map[string]string{"passwordEnv": "ENVMANAGER_TEST_UNSET_PASSWORD"},
+			inputs: []string{"1234"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["path"] = internal.GetTestDataFile(t, "keepass.kdbx")
+			storage, err := CreateStorageAdapter("keepassFromEnv", Storage{StorageType: KeepassTypeIdentifier, Config: tt.config})
+			if err != nil {
+				t.Fatalf("CreateStorageAdapter() returned error %v", err)
+			}
+			helper.GetInput().Inputs = tt.inputs
+			assertEntryAttribute(t, storage, "entry1", "UserName", "user1")
+		})
+	}
+}
+
+func TestCreateStorageAdapter_invalidPasswordless(t *testing.T) {
+	_, err := CreateStorageAdapter("keepass01", Storage{
+		StorageType: KeepassTypeIdentifier,
+		Config:      map[string]string{"path": "/tmp/keepass.kdbx", "passwordless": "maybe"},
+	})
+	if err == nil {
+		t.Error("CreateStorageAdapter() with invalid passwordless value got no error but wanted one")
+	}
+}
+
+func TestKeepass_ListEntries(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		want   []string
+	}{
+		{name: "All entries", prefix: "", want: []string{"entry1", "group1/g1e1"}},
+		{name: "Group prefix", prefix: "group1/", want: []string{"group1/g1e1"}},
+		{name: "Partial group name", prefix: "gr", want: []string{"group1/g1e1"}},
+		{name: "No match", prefix: "missing", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &Keepass{Name: "keepass01", FilePath: internal.GetTestDataFile(t, "keepass.kdbx")}
+			helper.GetInput().Inputs = []string{"1234"}
+			got, err := k.ListEntries(tt.prefix)
+			if err != nil {
+				t.Fatalf("ListEntries() returned error %v", err)
+			}
+			assert.Equalf(t, tt.want, got, "ListEntries()")
+		})
+	}
+}
+
+func TestKeepass_SetEntry(t *testing.T) {
+	data, err := os.ReadFile(internal.GetTestDataFile(t, "keepass.kdbx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	databaseFile := filepath.Join(t.TempDir(), "keepass.kdbx")
+	if err := os.WriteFile(databaseFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		key        string
+		attributes map[string]string
+		want       map[string]string
+	}{
+		{
+			name:       "Update existing entry",
+			key:        "entry1",
+			attributes: map[string]string{"Password": "rotated"},
+			want:       map[string]string{"UserName": "user1", "Password": "rotated"},
+		},
+		{
+			name:       "Create entry in new group",
+			key:        "group2/sub/new",
+			attributes: map[string]string{"UserName": "new-user", "Password": "new-pass"},
+			want:       map[string]string{"Title": "new", "UserName": "new-user", "Password": "new-pass"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &Keepass{Name: "keepass01", FilePath: databaseFile}
+			entry := NewEntry()
+			for name, value := range tt.attributes {
+				_ = entry.SetAttribute(name, value)
+			}
+			helper.GetInput().Inputs = []string{"1234"}
+			assert.NoError(t, k.SetEntry(tt.key, &entry), "SetEntry()")
+
+			// read the written database again
+			reopened := &Keepass{Name: "keepass01", FilePath: databaseFile}
+			helper.GetInput().Inputs = []string{"1234"}
+			got, err := reopened.GetEntry(tt.key)
+			if err != nil {
+				t.Fatalf("GetEntry() returned error %v", err)
+			}
+			for name, want := range tt.want {
+				value, err := got.GetAttribute(name)
+				assert.NoError(t, err, "GetAttribute(%s)", name)
+				if err == nil {
+					assert.Equalf(t, want, *value, "GetAttribute(%s)", name)
+				}
+			}
+		})
+	}
+
+	t.Run("Previous version is kept in the history", func(t *testing.T) {
+		k := &Keepass{Name: "keepass01", FilePath: databaseFile}
+		helper.GetInput().Inputs = []string{"1234"}
+		assert.NoError(t, k.Unlock("1234"), "Unlock()")
+		kpEntry := findEntryPointer(&k.database.Content.Root.Groups[0], "entry1")
+		if assert.NotNil(t, kpEntry, "entry1") && assert.Len(t, kpEntry.Histories, 1, "Histories") {
+			assert.Equal(t, "pass1", kpEntry.Histories[0].Entries[len(kpEntry.Histories[0].Entries)-1].GetPassword(), "password in history")
+		}
+	})
+}
+
+// writeTestDatabase creates a database with the password 1234 in a temporary directory. addEntries adds the entries
+// to the root group of the database.
+func writeTestDatabase(t *testing.T, version gokeepasslib.DatabaseOption, addEntries func(database *gokeepasslib.Database)) string {
+	t.Helper()
+	database := gokeepasslib.NewDatabase(version)
+	database.Credentials = gokeepasslib.NewPasswordCredentials("1234")
+	addEntries(database)
+
+	databaseFile := filepath.Join(t.TempDir(), "test.kdbx")
+	file, err := os.Create(databaseFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer file.Close()
+	if err := database.LockProtectedEntries(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gokeepasslib.NewEncoder(file).Encode(database); err != nil {
+		t.Fatal(err)
+	}
+	return databaseFile
+}
+
+// newTestEntry creates a keepass entry with the values
+func newTestEntry(values map[string]string) gokeepasslib.Entry {
+	entry := gokeepasslib.NewEntry()
+	for key, value := range values {
+		entry.Values = append(entry.Values, gokeepasslib.ValueData{Key: key, Value: gokeepasslib.V{Content: value}})
+	}
+	return entry
+}
+
+func TestKeepass_GetEntry_attachments(t *testing.T) {
+	const certificate = "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"
+	// valid base64, which must not be decoded again
+	const token = "dG9rZW4="
+
+	tests := []struct {
+		name    string
+		version gokeepasslib.DatabaseOption
+	}{
+		{name: "KDBX 3.1", version: gokeepasslib.WithDatabaseKDBXVersion3()},
+		{name: "KDBX 4", version: gokeepasslib.WithDatabaseKDBXVersion4()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			databaseFile := writeTestDatabase(t, tt.version, func(database *gokeepasslib.Database) {
+				entry := newTestEntry(map[string]string{"Title": "service", "UserName": "svc"})
+				entry.Binaries = append(
+					entry.Binaries,
+					database.AddBinary([]byte(certificate)).CreateReference("client.pem"),
+					database.AddBinary([]byte(token)).CreateReference("token"),
+				)
+				root := &database.Content.Root.Groups[0]
+				root.Entries = append(root.Entries, entry)
+			})
+
+			k := &Keepass{Name: "attachments", FilePath: databaseFile}
+			helper.GetInput().Inputs = []string{"1234"}
+			entry, err := k.GetEntry("service")
+			if err != nil {
+				t.Fatalf("GetEntry() returned error %v", err)
+			}
+			want := map[string]string{
+				"UserName":              "svc",
+				"attachment:client.pem": certificate,
+				"attachment:token":      token,
+			}
+			for name, wantValue := range want {
+				value, err := entry.GetAttribute(name)
+				if assert.NoError(t, err, "GetAttribute(%s)", name) {
+					assert.Equalf(t, wantValue, *value, "GetAttribute(%s)", name)
+				}
+			}
+		})
+	}
+}
+
+func TestKeepass_SetEntry_attachment(t *testing.T) {
+	k := &Keepass{Name: "keepass01", FilePath: internal.GetTestDataFile(t, "keepass.kdbx")}
+	entry := NewEntry()
+	_ = entry.SetAttribute("attachment:client.pem", "content")
+	assert.Error(t, k.SetEntry("entry1", &entry), "SetEntry()")
+}
+
+func TestKeepass_SetEntry_renewsSeeds(t *testing.T) {
+	tests := []struct {
+		name    string
+		version gokeepasslib.DatabaseOption
+	}{
+		{name: "KDBX 3.1", version: gokeepasslib.WithDatabaseKDBXVersion3()},
+		{name: "KDBX 4", version: gokeepasslib.WithDatabaseKDBXVersion4()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			databaseFile := writeTestDatabase(t, tt.version, func(database *gokeepasslib.Database) {
+				entry := newTestEntry(map[string]string{"Title": "service"})
+				entry.Values = append(entry.Values, gokeepasslib.ValueData{
+					Key:   "Password",
+					Value: gokeepasslib.V{Content: "secret", Protected: wrappers.NewBoolWrapper(true)},
+				})
+				root := &database.Content.Root.Groups[0]
+				root.Entries = append(root.Entries, entry)
+			})
+			k := &Keepass{Name: "seeds", FilePath: databaseFile}
+
+			// seeds returns the random values of the header of the saved database
+			seeds := func() [][]byte {
+				t.Helper()
+				database, err := k.decodeDatabase(gokeepasslib.NewPasswordCredentials("1234"))
+				if err != nil {
+					t.Fatalf("the saved database cannot be decoded: %v", err)
+				}
+				fileHeaders := database.Header.FileHeaders
+				if database.Header.IsKdbx4() {
+					return [][]byte{fileHeaders.MasterSeed, fileHeaders.EncryptionIV, database.Content.InnerHeader.InnerRandomStreamKey}
+				}
+				return [][]byte{fileHeaders.MasterSeed, fileHeaders.EncryptionIV, fileHeaders.ProtectedStreamKey, fileHeaders.StreamStartBytes}
+			}
+
+			var versions [][][]byte
+			for _, userName := range []string{"first", "second"} {
+				entry := NewEntry()
+				_ = entry.SetAttribute("UserName", userName)
+				helper.GetInput().Inputs = []string{"1234"}
+				if err := k.SetEntry("service", &entry); err != nil {
+					t.Fatalf("SetEntry() returned error %v", err)
+				}
+				versions = append(versions, seeds())
+			}
+			for i := range versions[0] {
+				assert.NotEqualf(t, versions[0][i], versions[1][i], "seed %d was reused", i)
+			}
+
+			// the protected values are still readable
+			reopened := &Keepass{Name: "seeds", FilePath: databaseFile}
+			helper.GetInput().Inputs = []string{"1234"}
+			assertEntryAttribute(t, reopened, "service", "Password", "secret")
+			assertEntryAttribute(t, reopened, "service", "UserName", "second")
+		})
+	}
+}
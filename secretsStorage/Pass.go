@@ -4,17 +4,33 @@ import (
 	"context"
 	"github.com/gopasspw/gopass/pkg/gopass"
 	"github.com/gopasspw/gopass/pkg/gopass/api"
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
 	"gopkg.in/errgo.v2/fmt/errors"
+	"slices"
+	"sort"
+	"strings"
 )
 
 const PassTypeIdentifier = "pass"
 
+func init() {
+	RegisterStorageAdapterType(PassTypeIdentifier, newPass, (&Pass{}).GetDefaultConfig())
+}
+
 type Pass struct {
 	Name   string
 	Prefix string
 	store  gopass.Store
 }
 
+// newPass creates a Pass storage adapter from its config
+func newPass(name string, config Storage) (StorageAdapter, error) {
+	return &Pass{
+		Name:   name,
+		Prefix: config.Config["prefix"],
+	}, nil
+}
+
 func (p *Pass) GetEntry(key string) (*Entry, error) {
 	if err := p.initStore(); err != nil {
 		return nil, err
@@ -41,6 +57,64 @@ func (p *Pass) GetEntry(key string) (*Entry, error) {
 	}
 	return &entry, nil
 }
+
+func (p *Pass) ListEntries(prefix string) ([]string, error) {
+	if err := p.initStore(); err != nil {
+		return nil, err
+	}
+	names, err := p.store.List(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, name := range names {
+		if p.Prefix != "" {
+			var inPrefix bool
+			name, inPrefix = strings.CutPrefix(name, p.Prefix+"/")
+			if !inPrefix {
+				continue
+			}
+		}
+		if strings.HasPrefix(name, prefix) {
+			paths = append(paths, name)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (p *Pass) SetEntry(key string, entry *Entry) error {
+	if err := p.initStore(); err != nil {
+		return err
+	}
+	if p.Prefix != "" {
+		key = p.Prefix + "/" + key
+	}
+	names, err := p.store.List(context.Background())
+	if err != nil {
+		return err
+	}
+	var secret gopass.Secret = secrets.NewAKV()
+	if slices.Contains(names, key) {
+		// update the existing secret, so attributes which are not set are kept
+		secret, err = p.store.Get(context.Background(), key, "")
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range entry.GetAttributeNames() {
+		value, _ := entry.GetAttribute(name)
+		if name == "password" {
+			secret.SetPassword(*value)
+			continue
+		}
+		if err := secret.Set(name, *value); err != nil {
+			return err
+		}
+	}
+	return p.store.Set(context.Background(), key, secret)
+}
+
 func (p *Pass) initStore() error {
 	if p.store == nil {
 		var err error
@@ -7,6 +7,7 @@ import (
 	"github.com/gopasspw/gopass/pkg/gopass/api"
 	"github.com/gopasspw/gopass/pkg/gopass/secrets"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"gopkg.in/errgo.v2/fmt/errors"
 	"reflect"
 	"testing"
@@ -47,7 +48,7 @@ func TestPass_GetEntry_successful(t *testing.T) {
 	p := &Pass{
 		store: goPassMock,
 	}
-	want := &Entry{map[string]string{
+	want := &Entry{attributes: map[string]string{
 		"password": "pass",
 		"username": "john.doe",
 	}}
@@ -131,7 +132,7 @@ func TestPass_GetEntry_successfulWithPrefix(t *testing.T) {
 		Prefix: "personal",
 		store:  goPassMock,
 	}
-	want := &Entry{map[string]string{
+	want := &Entry{attributes: map[string]string{
 		"password": "pass",
 		"username": "john.doe",
 	}}
@@ -203,3 +204,77 @@ func TestPass_initStore(t *testing.T) {
 		})
 	}
 }
+
+func TestPass_ListEntries(t *testing.T) {
+	tests := []struct {
+		name        string
+		storePrefix string
+		prefix      string
+		want        []string
+	}{
+		{name: "All entries", prefix: "", want: []string{"personal/key1", "personal/key2", "work/key1"}},
+		{name: "Prefix", prefix: "personal/", want: []string{"personal/key1", "personal/key2"}},
+		{name: "Storage prefix is removed", storePrefix: "personal", prefix: "", want: []string{"key1", "key2"}},
+		{name: "No match", prefix: "missing", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goPassMock := new(internal.MockGoPass)
+			goPassMock.On("List", context.Background()).Return([]string{"work/key1", "personal/key2", "personal/key1"}, nil)
+			p := &Pass{
+				Prefix: tt.storePrefix,
+				store:  goPassMock,
+			}
+
+			got, err := p.ListEntries(tt.prefix)
+
+			assert.NoError(t, err, "No error occurred")
+			assert.Equal(t, tt.want, got, "Entries are listed correctly")
+		})
+	}
+}
+
+func TestPass_SetEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing gopass.Secret
+		want     string
+	}{
+		{
+			name: "New entry",
+			want: "rotated\nusername: john.doe\n",
+		},
+		{
+			name:     "Existing entry keeps attributes",
+			existing: secrets.NewAKVWithData("pass", map[string][]string{"url": {"example.com"}}, "", false),
+			want:     "rotated\nurl: example.com\nusername: john.doe\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goPassMock := new(internal.MockGoPass)
+			names := []string{"personal/other"}
+			if tt.existing != nil {
+				names = append(names, "personal/key1")
+				goPassMock.On("Get", context.Background(), "personal/key1", "").Return(tt.existing, nil)
+			}
+			goPassMock.On("List", context.Background()).Return(names, nil)
+			var written string
+			goPassMock.On("Set", context.Background(), "personal/key1", mock.Anything).Run(func(args mock.Arguments) {
+				written = string(args.Get(2).(gopass.Byter).Bytes())
+			}).Return(nil)
+			p := &Pass{
+				Prefix: "personal",
+				store:  goPassMock,
+			}
+			entry := NewEntry()
+			_ = entry.SetAttribute("password", "rotated")
+			_ = entry.SetAttribute("username", "john.doe")
+
+			err := p.SetEntry("key1", &entry)
+
+			assert.NoError(t, err, "No error occurred")
+			assert.Equal(t, tt.want, written, "Secret is written correctly")
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package secretsStorage
+
+import (
+	"envManager/storagePlugin"
+	"gopkg.in/errgo.v2/fmt/errors"
+)
+
+// Plugin is a storage adapter implemented by an external executable, see the package storagePlugin. The plugin is
+// started for every call.
+type Plugin struct {
+	Name        string
+	StorageType string
+	Config      map[string]string
+	client      *storagePlugin.Client
+	//caseSensitive caches the answer of the plugin to IsCaseSensitive
+	caseSensitive *bool
+}
+
+// newPlugin creates the storage adapter for the plugin of the storage type, if there is one on the PATH
+func newPlugin(name string, config Storage) (*Plugin, error) {
+	executable, err := storagePlugin.FindPlugin(config.StorageType)
+	if err != nil {
+		return nil, errors.Newf("Unknown storage type %s", config.StorageType)
+	}
+	return &Plugin{
+		Name:        name,
+		StorageType: config.StorageType,
+		Config:      config.Config,
+		client:      &storagePlugin.Client{Executable: executable},
+	}, nil
+}
+
+func (p *Plugin) GetEntry(key string) (*Entry, error) {
+	response, err := p.call(storagePlugin.MethodGetEntry, func(request *storagePlugin.Request) {
+		request.Key = key
+	})
+	if err != nil {
+		return nil, err
+	}
+	entry := NewEntry()
+	for name, value := range response.Attributes {
+		if err := entry.SetAttribute(name, value); err != nil {
+			return nil, err
+		}
+	}
+	return &entry, nil
+}
+
+func (p *Plugin) IsCaseSensitive() bool {
+	if p.caseSensitive == nil {
+		response, err := p.call(storagePlugin.MethodIsCaseSensitive, nil)
+		// assume the safe default if the plugin does not answer
+		caseSensitive := err != nil || response.CaseSensitive
+		p.caseSensitive = &caseSensitive
+	}
+	return *p.caseSensitive
+}
+
+func (p *Plugin) Validate() (error, []string) {
+	out := []string{"Plugin executable is " + p.client.Executable}
+	response, err := p.call(storagePlugin.MethodValidate, nil)
+	if response != nil {
+		out = append(out, response.Messages...)
+	}
+	if err != nil {
+		out = append(out, err.Error())
+		return errors.Newf("Validation of %s failed. Run debug storage %s to check it in detail", p.Name, p.Name), out
+	}
+	return nil, out
+}
+
+// ValidateConfig does not start the plugin, it was found on the PATH when creating the storage adapter
+func (p *Plugin) ValidateConfig() error {
+	return nil
+}
+
+func (p *Plugin) GetDefaultConfig() map[string]string {
+	response, err := p.call(storagePlugin.MethodGetDefaultConfig, nil)
+	if err != nil || response.Config == nil {
+		return map[string]string{}
+	}
+	return response.Config
+}
+
+// ListEntries returns the paths of the entries starting with prefix, if the plugin supports listing
+func (p *Plugin) ListEntries(prefix string) ([]string, error) {
+	response, err := p.call(storagePlugin.MethodListEntries, func(request *storagePlugin.Request) {
+		request.Prefix = prefix
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.Entries, nil
+}
+
+// call sends a request for method to the plugin. modify can add the arguments of the method to the request.
+func (p *Plugin) call(method string, modify func(request *storagePlugin.Request)) (*storagePlugin.Response, error) {
+	request := storagePlugin.Request{Method: method, Name: p.Name, Config: p.Config}
+	if modify != nil {
+		modify(&request)
+	}
+	response, err := p.client.Call(request)
+	if err != nil {
+		return response, err
+	}
+	if response.Unsupported {
+		return response, errors.Newf("The plugin of %s does not support %s", p.StorageType, method)
+	}
+	return response, nil
+}
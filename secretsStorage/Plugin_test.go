@@ -0,0 +1,89 @@
+package secretsStorage
+
+import (
+	"envManager/internal"
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	internal.ServeTestPlugin()
+	os.Exit(m.Run())
+}
+
+func TestPlugin_GetEntry(t *testing.T) {
+	internal.InstallTestPlugin(t, "testplugin")
+	storage, err := CreateStorageAdapter("plugin", Storage{StorageType: "testplugin", Config: map[string]string{"valid": "true"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := storage.GetEntry("group1/g1e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"UserName": "g1e1-user", "Password": "g1e1-pass"}
+	if !reflect.DeepEqual(entry.attributes, want) {
+		t.Errorf("GetEntry() got = %v, want %v", entry.attributes, want)
+	}
+	if _, err := storage.GetEntry("missing"); err == nil {
+		t.Errorf("GetEntry() of a missing entry did not fail")
+	}
+	if storage.IsCaseSensitive() {
+		t.Errorf("IsCaseSensitive() got true, want false")
+	}
+	entries, err := storage.(*Plugin).ListEntries("")
+	if err != nil || !reflect.DeepEqual(entries, []string{"entry1", "group1/g1e1"}) {
+		t.Errorf("ListEntries() got = %v, %v", entries, err)
+	}
+}
+
+func TestPlugin_Validate(t *testing.T) {
+	internal.InstallTestPlugin(t, "testplugin")
+	tests := []struct {
+		name    string
+		config  map[string]string
+		wantOut int
+		wantErr bool
+	}{
+		{name: "Valid", config: map[string]string{"valid": "true"}, wantOut: 2},
+		// the error of the plugin is shown as well
+		{name: "Invalid", config: map[string]string{"valid": "false"}, wantOut: 3, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the plugin is not started when creating the storage adapter
+			storage, err := CreateStorageAdapter("plugin", Storage{StorageType: "testplugin", Config: tt.config})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err, out := storage.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(out) != tt.wantOut {
+				t.Errorf("Validate() got messages %v, want %d", out, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestPlugin_discovery(t *testing.T) {
+	internal.InstallTestPlugin(t, "testplugin")
+	internal.InstallTestPlugin(t, KeepassTypeIdentifier)
+	types := GetStorageAdapterTypes()
+	if !slices.Contains(types, "testplugin") {
+		t.Errorf("GetStorageAdapterTypes() got %v, want it to contain testplugin", types)
+	}
+	if len(types) != len(slices.Compact(slices.Sorted(slices.Values(types)))) {
+		t.Errorf("GetStorageAdapterTypes() got duplicates %v", types)
+	}
+	config, err := GetStorageAdapterDefaultConfig("testplugin")
+	if err != nil || !reflect.DeepEqual(config, map[string]string{"valid": "true"}) {
+		t.Errorf("GetStorageAdapterDefaultConfig() got = %v, %v", config, err)
+	}
+	if _, err := CreateStorageAdapter("plugin", Storage{StorageType: "missingplugin"}); err == nil {
+		t.Errorf("CreateStorageAdapter() of an unknown type did not fail")
+	}
+}
@@ -1,21 +1,189 @@
 package secretsStorage
 
 import (
+	"cmp"
 	"envManager/environment"
 	"envManager/helper"
 	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
 	"slices"
+	"strconv"
+	"strings"
 
 	"gopkg.in/errgo.v2/fmt/errors"
 )
 
 type Profile struct {
-	name      string
-	Storage   string            `yaml:"storage"`
-	Path      string            `yaml:"path"`
-	ConstEnv  map[string]string `yaml:"constEnv,omitempty"`
-	Env       map[string]string `yaml:"env,omitempty"`
-	DependsOn []string          `yaml:"dependsOn,omitempty"`
+	name     string
+	Storage  string            `yaml:"storage"`
+	Path     string            `yaml:"path"`
+	ConstEnv map[string]string `yaml:"constEnv,omitempty"`
+	//Env maps variable names to attributes of the entry at Path in Storage
+	Env map[string]string `yaml:"-"`
+	//EnvRefs maps variable names to attributes of entries in other storages or at other paths. In the config file, they
+	//are written to env next to the variables of Env.
+	EnvRefs map[string]EnvReference `yaml:"-"`
+	//Files maps variable names to attributes written to files, the variables hold the paths of the files
+	Files map[string]FileReference `yaml:"files,omitempty"`
+	//Lists maps list variables like PATH to the items added to them
+	Lists     map[string]ListOperation `yaml:"lists,omitempty"`
+	DependsOn []string                 `yaml:"dependsOn,omitempty"`
+}
+
+// EnvReference references an attribute of an entry. Storage and Path default to the ones of the profile.
+type EnvReference struct {
+	Storage   string `yaml:"storage,omitempty"`
+	Path      string `yaml:"path,omitempty"`
+	Attribute string `yaml:"attribute"`
+}
+
+// FileReference references an attribute which is written to a file in the session directory, see
+// environment.Environment.GetSessionDir. Storage and Path default to the ones of the profile.
+type FileReference struct {
+	Storage   string   `yaml:"storage,omitempty"`
+	Path      string   `yaml:"path,omitempty"`
+	Attribute string   `yaml:"attribute"`
+	Mode      FileMode `yaml:"mode,omitempty"`
+}
+
+// ListOperation adds items to a list variable like PATH, keeping its current value. A leading ~/ of an item is
+// replaced with the home directory of the user. Separator defaults to the one of PATH.
+type ListOperation struct {
+	Prepend   []string `yaml:"prepend,omitempty"`
+	Append    []string `yaml:"append,omitempty"`
+	Separator string   `yaml:"separator,omitempty"`
+}
+
+// FileMode is the permission of a file. In the config file, it is an octal number like 0600 or a string like "0600".
+type FileMode os.FileMode
+
+// defaultFileMode is the mode of files without a mode
+const defaultFileMode FileMode = 0600
+
+// UnmarshalYAML accepts numbers (which YAML reads as octal if they start with 0) and octal strings
+func (m *FileMode) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var number uint32
+	if err := unmarshal(&number); err == nil {
+		*m = FileMode(number)
+	} else {
+		var text string
+		if err := unmarshal(&text); err != nil {
+			return err
+		}
+		parsed, err := strconv.ParseUint(text, 8, 32)
+		if err != nil {
+			return errors.Newf("invalid file mode %s, use an octal number like 0600", text)
+		}
+		*m = FileMode(parsed)
+	}
+	if os.FileMode(*m)&^os.ModePerm != 0 {
+		return errors.Newf("invalid file mode %o, only permission bits are allowed", uint32(*m))
+	}
+	return nil
+}
+
+// MarshalYAML writes the mode as octal string, as YAML writers do not write octal numbers
+func (m FileMode) MarshalYAML() (interface{}, error) {
+	return fmt.Sprintf("%04o", uint32(m)), nil
+}
+
+// profileYAML is the representation of a profile in the config file. The values of env are either the name of an
+// attribute or an EnvReference.
+type profileYAML struct {
+	Storage   string                   `yaml:"storage"`
+	Path      string                   `yaml:"path"`
+	ConstEnv  map[string]string        `yaml:"constEnv,omitempty"`
+	Env       map[string]envValueYAML  `yaml:"env,omitempty"`
+	Files     map[string]FileReference `yaml:"files,omitempty"`
+	Lists     map[string]ListOperation `yaml:"lists,omitempty"`
+	DependsOn []string                 `yaml:"dependsOn,omitempty"`
+}
+
+// envValueYAML is a value of env in the config file
+type envValueYAML struct {
+	attribute string
+	reference *EnvReference
+}
+
+// UnmarshalYAML accepts the name of an attribute as well as an EnvReference
+func (v *envValueYAML) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var attribute string
+	if err := unmarshal(&attribute); err == nil {
+		*v = envValueYAML{attribute: attribute}
+		return nil
+	}
+	var reference EnvReference
+	if err := unmarshal(&reference); err != nil {
+		return err
+	}
+	if reference.Attribute == "" {
+		return errors.New("the reference of an env value must have an attribute")
+	}
+	*v = envValueYAML{reference: &reference}
+	return nil
+}
+
+// MarshalYAML writes the name of the attribute or the EnvReference
+func (v envValueYAML) MarshalYAML() (interface{}, error) {
+	if v.reference != nil {
+		return v.reference, nil
+	}
+	return v.attribute, nil
+}
+
+// UnmarshalYAML splits the values of env into Env and EnvRefs
+func (p *Profile) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var profile profileYAML
+	if err := unmarshal(&profile); err != nil {
+		return err
+	}
+	*p = Profile{
+		Storage:   profile.Storage,
+		Path:      profile.Path,
+		ConstEnv:  profile.ConstEnv,
+		Files:     profile.Files,
+		Lists:     profile.Lists,
+		DependsOn: profile.DependsOn,
+	}
+	for key, value := range profile.Env {
+		if value.reference == nil {
+			if p.Env == nil {
+				p.Env = map[string]string{}
+			}
+			p.Env[key] = value.attribute
+			continue
+		}
+		if p.EnvRefs == nil {
+			p.EnvRefs = map[string]EnvReference{}
+		}
+		p.EnvRefs[key] = *value.reference
+	}
+	return nil
+}
+
+// MarshalYAML writes Env and EnvRefs to env
+func (p Profile) MarshalYAML() (interface{}, error) {
+	profile := profileYAML{
+		Storage:   p.Storage,
+		Path:      p.Path,
+		ConstEnv:  p.ConstEnv,
+		Files:     p.Files,
+		Lists:     p.Lists,
+		DependsOn: p.DependsOn,
+	}
+	if len(p.Env)+len(p.EnvRefs) > 0 {
+		profile.Env = map[string]envValueYAML{}
+	}
+	for key, attribute := range p.Env {
+		profile.Env[key] = envValueYAML{attribute: attribute}
+	}
+	for key, reference := range p.EnvRefs {
+		profile.Env[key] = envValueYAML{reference: &reference}
+	}
+	return profile, nil
 }
 
 // Validate checks the validity of the profile. The storage and all profiles this
@@ -26,6 +194,30 @@ func (p *Profile) Validate() []string {
 	if !registry.HasStorage(p.Storage) {
 		out = append(out, fmt.Sprintf("references storage %s which is not defined", p.Storage))
 	}
+	for _, key := range slices.Sorted(maps.Keys(p.EnvRefs)) {
+		storage := p.EnvRefs[key].Storage
+		if storage != "" && !registry.HasStorage(storage) {
+			out = append(out, fmt.Sprintf("references storage %s for %s which is not defined", storage, key))
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(p.Files)) {
+		storage := p.Files[key].Storage
+		if storage != "" && !registry.HasStorage(storage) {
+			out = append(out, fmt.Sprintf("references storage %s for file %s which is not defined", storage, key))
+		}
+	}
+	// a variable set by two sections would be pushed twice, so unload would restore the wrong value
+	for _, key := range slices.Sorted(maps.Keys(p.Files)) {
+		_, inLists := p.Lists[key]
+		if p.setsVariable(key) || inLists {
+			out = append(out, fmt.Sprintf("sets %s and writes a file for it", key))
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(p.Lists)) {
+		if p.setsVariable(key) {
+			out = append(out, fmt.Sprintf("sets %s and adds items to it as list", key))
+		}
+	}
 	for i := 0; i < len(p.DependsOn); i++ {
 		if !registry.HasProfile(p.DependsOn[i]) {
 			out = append(out, fmt.Sprintf("depends on %s which is not defined", p.DependsOn[i]))
@@ -34,60 +226,226 @@ func (p *Profile) Validate() []string {
 	return out
 }
 
+// setsVariable checks if constEnv or env of the profile set the variable key
+func (p *Profile) setsVariable(key string) bool {
+	_, inConstEnv := p.ConstEnv[key]
+	_, inEnv := p.Env[key]
+	_, inEnvRefs := p.EnvRefs[key]
+	return inConstEnv || inEnv || inEnvRefs
+}
+
 // AddToEnvironment adds the environment variables defined by this profile to the
-// given environment.Environment instance.
+// given environment.Environment instance. The values they had before are recorded,
+// so RemoveFromEnvironment can restore them.
+//
+// Values of ConstEnv and Env containing {{ are text/template templates. The data of
+// the templates are the attributes of the entry, e.g. {{.Password}}, and the functions
+// env, var, attr, urlencode, base64 and json are available.
 func (p *Profile) AddToEnvironment(env *environment.Environment) error {
-	// load constEnv
-	for key, value := range p.ConstEnv {
-		err := env.Set(key, value)
+	values := newProfileValues(p, env)
+	// all values are rendered before they are added, so templates see the environment
+	// as it was before loading this profile
+	keys := slices.Concat(
+		slices.Sorted(maps.Keys(p.ConstEnv)),
+		slices.Sorted(maps.Keys(p.Env)),
+		slices.Sorted(maps.Keys(p.EnvRefs)),
+	)
+	for _, key := range keys {
+		if _, err := values.get(key); err != nil {
+			return err
+		}
+	}
+	for _, key := range keys {
+		err := env.Push(p.name, key, values.values[key])
 		if err != nil {
 			return err
 		}
 	}
+	if err := p.addFilesToEnvironment(env, values.entries); err != nil {
+		return err
+	}
+	return p.addListsToEnvironment(env)
+}
 
-	// load env from storage
-	if len(p.Env) > 0 {
-		storage, err := GetRegistry().GetStorage(p.Storage)
+// addListsToEnvironment adds the items of Lists to the list variables
+func (p *Profile) addListsToEnvironment(env *environment.Environment) error {
+	for _, key := range slices.Sorted(maps.Keys(p.Lists)) {
+		list := p.Lists[key]
+		separator := cmp.Or(list.Separator, string(os.PathListSeparator))
+		prependItems, err := expandListItems(list.Prepend)
 		if err != nil {
 			return err
 		}
-		entry, err := (*storage).GetEntry(p.Path)
+		appendItems, err := expandListItems(list.Append)
 		if err != nil {
-			return fmt.Errorf("failed to load entry '%s': %w", p.Path, err)
+			return err
 		}
-		for key, attributeName := range p.Env {
-			value, err := entry.GetAttribute(attributeName)
-			if err != nil {
-				return err
-			}
-			err = env.Set(key, *value)
+		if err := env.AddToList(p.name, key, separator, prependItems, appendItems); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandListItems replaces a leading ~/ of the items with the home directory of the user
+func expandListItems(items []string) ([]string, error) {
+	expanded := make([]string, 0, len(items))
+	for _, item := range items {
+		if strings.HasPrefix(item, "~/") {
+			home, err := os.UserHomeDir()
 			if err != nil {
-				return err
+				return nil, err
 			}
+			item = filepath.Join(home, item[2:])
+		}
+		expanded = append(expanded, item)
+	}
+	return expanded, nil
+}
+
+// addFilesToEnvironment writes the attributes of Files to the session directory and
+// adds the paths of the files to the environment
+func (p *Profile) addFilesToEnvironment(env *environment.Environment, entries profileEntries) error {
+	if len(p.Files) == 0 {
+		return nil
+	}
+	sessionDir, err := env.GetSessionDir()
+	if err != nil {
+		return err
+	}
+	for _, key := range slices.Sorted(maps.Keys(p.Files)) {
+		reference := p.Files[key]
+		value, err := entries.getAttribute(
+			cmp.Or(reference.Storage, p.Storage),
+			cmp.Or(reference.Path, p.Path),
+			reference.Attribute,
+		)
+		if err != nil {
+			return err
+		}
+		// check the name before writing the file, Push would do it afterward
+		if err := environment.ValidateVariableName(key); err != nil {
+			return err
+		}
+		path := p.getFilePath(sessionDir, key)
+		mode := os.FileMode(cmp.Or(reference.Mode, defaultFileMode))
+		if err := writeSessionFile(path, value, mode); err != nil {
+			return fmt.Errorf("failed to write the file of %s: %w", key, err)
+		}
+		if err := env.Push(p.name, key, path); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// getFilePath returns the path of the file of the variable key in the session directory
+func (p *Profile) getFilePath(sessionDir string, key string) string {
+	// the profile name is escaped, so it cannot contain a path separator
+	return filepath.Join(sessionDir, url.PathEscape(p.name)+"."+key)
+}
+
+// writeSessionFile writes value to the file at path, replacing the file if it exists
+func writeSessionFile(path string, value string, mode os.FileMode) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(value)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	// the mode given to OpenFile is reduced by the umask
+	return os.Chmod(path, mode)
+}
+
+// profileEntries caches the entries loaded by a profile by their storage and path, so each entry is loaded once
+type profileEntries map[[2]string]*Entry
+
+// getEntry returns the entry at path in storage
+func (e profileEntries) getEntry(storageName string, path string) (*Entry, error) {
+	entry, loaded := e[[2]string{storageName, path}]
+	if loaded {
+		return entry, nil
+	}
+	storage, err := GetRegistry().GetStorage(storageName)
+	if err != nil {
+		return nil, err
+	}
+	entry, err = (*storage).GetEntry(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load entry '%s': %w", path, err)
+	}
+	e[[2]string{storageName, path}] = entry
+	return entry, nil
+}
+
+// getAttribute returns the attribute of the entry at path in storage
+func (e profileEntries) getAttribute(storageName string, path string, attributeName string) (string, error) {
+	entry, err := e.getEntry(storageName, path)
+	if err != nil {
+		return "", err
+	}
+	value, err := entry.GetAttribute(attributeName)
+	if err != nil {
+		return "", err
+	}
+	return *value, nil
+}
+
 // RemoveFromEnvironment removes the environment variables defined by this profile
-// from the given environment.Environment instance.
+// from the given environment.Environment instance. Variables which had a value
+// before the profile was loaded get this value back.
 func (p *Profile) RemoveFromEnvironment(env *environment.Environment) error {
 	// unload constEnv
 	for key := range p.ConstEnv {
-		err := env.Unset(key)
+		err := env.Pop(p.name, key)
 		if err != nil {
 			return err
 		}
 	}
 
 	// unload env from storage
-	if len(p.Env) > 0 {
-		for key := range p.Env {
-			err := env.Unset(key)
-			if err != nil {
+	for key := range p.Env {
+		err := env.Pop(p.name, key)
+		if err != nil {
+			return err
+		}
+	}
+	for key := range p.EnvRefs {
+		err := env.Pop(p.name, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	// remove the files
+	sessionDir, hasSessionDir := env.Lookup(environment.SessionDirVariableName)
+	for key := range p.Files {
+		if hasSessionDir {
+			err := os.Remove(p.getFilePath(sessionDir, key))
+			if err != nil && !os.IsNotExist(err) {
 				return err
 			}
 		}
+		err := env.Pop(p.name, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	// remove the items added to lists
+	for key := range p.Lists {
+		err := env.RemoveFromList(p.name, key)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
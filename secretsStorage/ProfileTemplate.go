@@ -0,0 +1,237 @@
+package secretsStorage
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"envManager/environment"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"gopkg.in/errgo.v2/fmt/errors"
+)
+
+// profileValues renders the values of the variables of a profile. Values of constEnv and env containing {{ are
+// templates, see AddToEnvironment. Every value is rendered once.
+type profileValues struct {
+	profile *Profile
+	env     *environment.Environment
+	entries profileEntries
+	values  map[string]string
+	//rendering holds the variables currently rendered, to detect variables referencing themselves
+	rendering []string
+}
+
+// newProfileValues creates the profileValues of the profile, env is the environment before the profile is loaded
+func newProfileValues(profile *Profile, env *environment.Environment) *profileValues {
+	return &profileValues{
+		profile: profile,
+		env:     env,
+		entries: profileEntries{},
+		values:  map[string]string{},
+	}
+}
+
+// isTemplate checks if value must be rendered as template
+func isTemplate(value string) bool {
+	return strings.Contains(value, "{{")
+}
+
+// get returns the value of the variable key of the profile
+func (v *profileValues) get(key string) (string, error) {
+	if value, rendered := v.values[key]; rendered {
+		return value, nil
+	}
+	if slices.Contains(v.rendering, key) {
+		cycle := append(slices.Clone(v.rendering[slices.Index(v.rendering, key):]), key)
+		return "", errors.Newf("The variables of profile %s reference each other: %s", v.profile.name, strings.Join(cycle, " -> "))
+	}
+	v.rendering = append(v.rendering, key)
+	defer func() {
+		v.rendering = v.rendering[:len(v.rendering)-1]
+	}()
+
+	var value string
+	var err error
+	if constValue, isConst := v.profile.ConstEnv[key]; isConst {
+		value = constValue
+		if isTemplate(constValue) {
+			value, err = v.render(key, constValue)
+		}
+	} else if attributeName, isEnv := v.profile.Env[key]; isEnv {
+		if isTemplate(attributeName) {
+			value, err = v.render(key, attributeName)
+		} else {
+			value, err = v.entries.getAttribute(v.profile.Storage, v.profile.Path, attributeName)
+		}
+	} else if reference, isReference := v.profile.EnvRefs[key]; isReference {
+		value, err = v.entries.getAttribute(
+			cmp.Or(reference.Storage, v.profile.Storage),
+			cmp.Or(reference.Path, v.profile.Path),
+			reference.Attribute,
+		)
+	} else {
+		return "", errors.Newf("Profile %s does not define the variable %s", v.profile.name, key)
+	}
+	if err != nil {
+		return "", err
+	}
+	v.values[key] = value
+	return value, nil
+}
+
+// render executes the template text of the variable key. The attributes of the entry of the profile are the data of
+// the template, they are only loaded if the template uses them.
+func (v *profileValues) render(key string, text string) (string, error) {
+	tmpl, err := template.New(key).Option("missingkey=error").Funcs(v.templateFuncs()).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template of %s: %w", key, err)
+	}
+	attributes := map[string]string{}
+	var entry *Entry
+	if usesTemplateData(tmpl.Tree.Root) {
+		entry, err = v.entries.getEntry(v.profile.Storage, v.profile.Path)
+		if err != nil {
+			return "", err
+		}
+		// attributes which cannot be read are missing, their errors are added if rendering fails
+		attributes = entry.readableAttributes()
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, attributes); err != nil {
+		if entry != nil {
+			// report why an attribute used by the template is missing
+			for _, name := range templateFieldNames(tmpl.Tree.Root) {
+				if attributeErr, isBroken := entry.attributeErrors[name]; isBroken {
+					return "", fmt.Errorf("failed to render %s: %w", key, attributeErr)
+				}
+			}
+		}
+		return "", fmt.Errorf("failed to render %s: %w", key, err)
+	}
+	return out.String(), nil
+}
+
+// templateFuncs returns the functions available in the templates of the profile
+func (v *profileValues) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// env returns a variable of the environment, including the variables of the profiles loaded before
+		"env": func(name string) (string, error) {
+			value, isSet := v.env.Lookup(name)
+			if !isSet {
+				return "", errors.Newf("The environment variable %s is not set", name)
+			}
+			return value, nil
+		},
+		// var returns another variable of the profile
+		"var": v.get,
+		// attr returns an attribute of the entry of the profile, for names which cannot be written as .name
+		"attr": func(name string) (string, error) {
+			return v.entries.getAttribute(v.profile.Storage, v.profile.Path, name)
+		},
+		"urlencode": url.QueryEscape,
+		"base64": func(value string) string {
+			return base64.StdEncoding.EncodeToString([]byte(value))
+		},
+		// json returns value as JSON string including the quotes
+		"json": func(value string) (string, error) {
+			encoded, err := json.Marshal(value)
+			return string(encoded), err
+		},
+	}
+}
+
+// usesTemplateData checks if the template node accesses the data of the template, i.e. the attributes of the entry
+func usesTemplateData(node parse.Node) bool {
+	switch typed := node.(type) {
+	case *parse.ListNode:
+		if typed == nil {
+			return false
+		}
+		return slices.ContainsFunc(typed.Nodes, usesTemplateData)
+	case *parse.ActionNode:
+		return usesTemplateData(typed.Pipe)
+	case *parse.PipeNode:
+		if typed == nil {
+			return false
+		}
+		for _, command := range typed.Cmds {
+			if usesTemplateData(command) {
+				return true
+			}
+		}
+		return false
+	case *parse.CommandNode:
+		return slices.ContainsFunc(typed.Args, usesTemplateData)
+	case *parse.IfNode:
+		return usesTemplateData(typed.Pipe) || usesTemplateData(typed.List) || usesTemplateData(typed.ElseList)
+	case *parse.RangeNode:
+		return usesTemplateData(typed.Pipe) || usesTemplateData(typed.List) || usesTemplateData(typed.ElseList)
+	case *parse.WithNode:
+		return usesTemplateData(typed.Pipe) || usesTemplateData(typed.List) || usesTemplateData(typed.ElseList)
+	case *parse.FieldNode, *parse.DotNode, *parse.ChainNode, *parse.VariableNode:
+		// variables may hold the data as well, e.g. $ or $x := .
+		return true
+	default:
+		return false
+	}
+}
+
+// templateFieldNames returns the names of the attributes the template node accesses as field, e.g. URL for .URL or
+// $.URL
+func templateFieldNames(node parse.Node) []string {
+	switch typed := node.(type) {
+	case *parse.ListNode:
+		if typed == nil {
+			return nil
+		}
+		var names []string
+		for _, child := range typed.Nodes {
+			names = append(names, templateFieldNames(child)...)
+		}
+		return names
+	case *parse.ActionNode:
+		return templateFieldNames(typed.Pipe)
+	case *parse.PipeNode:
+		if typed == nil {
+			return nil
+		}
+		var names []string
+		for _, command := range typed.Cmds {
+			names = append(names, templateFieldNames(command)...)
+		}
+		return names
+	case *parse.CommandNode:
+		var names []string
+		for _, arg := range typed.Args {
+			names = append(names, templateFieldNames(arg)...)
+		}
+		return names
+	case *parse.IfNode:
+		return templateBranchFieldNames(&typed.BranchNode)
+	case *parse.RangeNode:
+		return templateBranchFieldNames(&typed.BranchNode)
+	case *parse.WithNode:
+		return templateBranchFieldNames(&typed.BranchNode)
+	case *parse.FieldNode:
+		return typed.Ident[:1]
+	case *parse.VariableNode:
+		if len(typed.Ident) > 1 && typed.Ident[0] == "$" {
+			return typed.Ident[1:2]
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+// templateBranchFieldNames returns the templateFieldNames of an if, range or with node
+func templateBranchFieldNames(branch *parse.BranchNode) []string {
+	names := templateFieldNames(branch.Pipe)
+	names = append(names, templateFieldNames(branch.List)...)
+	return append(names, templateFieldNames(branch.ElseList)...)
+}
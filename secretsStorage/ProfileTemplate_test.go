@@ -0,0 +1,132 @@
+package secretsStorage
+
+import (
+	"envManager/environment"
+	"envManager/helper"
+	"envManager/internal"
+	"strings"
+	"testing"
+)
+
+func TestProfile_AddToEnvironment_templates(t *testing.T) {
+	const storageName = "templateKeepass"
+	_ = GetRegistry().AddStorage(storageName, &Keepass{
+		FilePath: internal.GetTestDataFile(t, "keepass.kdbx"),
+	})
+
+	tests := []struct {
+		name        string
+		profile     Profile
+		want        map[string]string
+		wantErrText string
+	}{
+		{
+			name: "Entry attributes",
+			profile: Profile{
+				Storage: storageName,
+				Path:    "entry1",
+				Env:     map[string]string{"DATABASE_URL": "postgres://{{.UserName}}:{{.Password}}@db:5432/app"},
+			},
+			want: map[string]string{"DATABASE_URL": "postgres://user1:pass1@db:5432/app"},
+		},
+		{
+			name: "Escape functions",
+			profile: Profile{
+				ConstEnv: map[string]string{
+					"URL":    `{{urlencode "p@ss w/rd"}}`,
+					"BASE64": `{{"user:pass" | base64}}`,
+					"JSON":   `{"password": {{json "a\"b"}}}`,
+				},
+			},
+			want: map[string]string{"URL": "p%40ss+w%2Frd", "BASE64": "dXNlcjpwYXNz", "JSON": `{"password": "a\"b"}`},
+		},
+		{
+			name: "Variables of the profile and the environment",
+			profile: Profile{
+				Storage:  storageName,
+				Path:     "entry1",
+				ConstEnv: map[string]string{"HOST": "db.{{env \"PRESET_DOMAIN\"}}", "URL": "{{var \"USER\"}}@{{var \"HOST\"}}"},
+				Env:      map[string]string{"USER": "UserName"},
+			},
+			want: map[string]string{"HOST": "db.example.com", "URL": "user1@db.example.com"},
+		},
+		{
+			name: "Attribute function",
+			profile: Profile{
+				Storage: storageName,
+				Path:    "entry1",
+				Env:     map[string]string{"ADVANCED": `{{attr "advanced1"}}`},
+			},
+			want: map[string]string{"ADVANCED": "advanced1-value"},
+		},
+		{
+			name: "Entry is not loaded without attributes",
+			profile: Profile{
+				Storage:  "null",
+				ConstEnv: map[string]string{"HOST": `{{env "PRESET_DOMAIN"}}`},
+			},
+			want: map[string]string{"HOST": "example.com"},
+		},
+		{
+			name: "Cycle",
+			profile: Profile{
+				ConstEnv: map[string]string{"A": `{{var "B"}}`, "B": `{{var "C"}}`, "C": `{{var "A"}}`},
+			},
+			wantErrText: "A -> B -> C -> A",
+		},
+		{
+			name: "Unknown variable",
+			profile: Profile{
+				ConstEnv: map[string]string{"A": `{{var "B"}}`},
+			},
+			wantErrText: "does not define the variable B",
+		},
+		{
+			name: "Unset environment variable",
+			profile: Profile{
+				ConstEnv: map[string]string{"A": `{{env "ENVMANAGER_TEST_UNSET"}}`},
+			},
+			wantErrText: "ENVMANAGER_TEST_UNSET is not set",
+		},
+		{
+			name: "Unknown attribute",
+			profile: Profile{
+				Storage: storageName,
+				Path:    "entry1",
+				Env:     map[string]string{"A": "{{.Unknown}}"},
+			},
+			wantErrText: "failed to render A",
+		},
+		{
+			name: "Invalid template",
+			profile: Profile{
+				ConstEnv: map[string]string{"A": "{{.Unclosed"},
+			},
+			wantErrText: "invalid template of A",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := environment.NewEnvironment()
+			_ = env.Set("PRESET_DOMAIN", "example.com")
+			helper.GetInput().Inputs = []string{"1234"}
+			tt.profile.name = "templates"
+
+			err := tt.profile.AddToEnvironment(&env)
+			if tt.wantErrText != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrText) {
+					t.Errorf("AddToEnvironment() error = %v, want an error containing %q", err, tt.wantErrText)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AddToEnvironment() returned error %v", err)
+			}
+			for key, want := range tt.want {
+				if got, _ := env.Lookup(key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
@@ -1,9 +1,15 @@
 package secretsStorage
 
 import (
+	"context"
 	"envManager/environment"
 	"envManager/helper"
 	"envManager/internal"
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+	"github.com/tobischo/gokeepasslib/v3"
+	"gopkg.in/yaml.v2"
+	"os"
+	"os/exec"
 	"reflect"
 	"testing"
 )
@@ -195,6 +201,72 @@ func TestProfile_AddToEnvironment(t *testing.T) {
 	}
 }
 
+// TestProfile_AddToEnvironment_shellSafeValues loads values containing shell metacharacters from a Pass and a Keepass
+// entry and evaluates the resulting statements in a real shell.
+func TestProfile_AddToEnvironment_shellSafeValues(t *testing.T) {
+	shell, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh available to evaluate the statements")
+	}
+	const password = "pa'ss\"w$(id)`id`\\\nörd;|&*"
+
+	goPassMock := new(internal.MockGoPass)
+	goPassMock.On("Get", context.Background(), "metachars", "").Return(
+		secrets.NewAKVWithData(password, map[string][]string{"username": {"jöhn \"the\" döe"}}, "", false),
+		nil,
+	)
+	_ = GetRegistry().AddStorage("shellSafePass", &Pass{store: goPassMock})
+
+	kpEntry := gokeepasslib.NewEntry()
+	kpEntry.Values = []gokeepasslib.ValueData{
+		{Key: "Title", Value: gokeepasslib.V{Content: "metachars"}},
+		{Key: "UserName", Value: gokeepasslib.V{Content: "jöhn 'the' döe"}},
+		{Key: "Password", Value: gokeepasslib.V{Content: password}},
+	}
+	database := gokeepasslib.NewDatabase()
+	database.Content.Root.Groups[0].Entries = []gokeepasslib.Entry{kpEntry}
+	_ = GetRegistry().AddStorage("shellSafeKeepass", &Keepass{database: database})
+
+	tests := []struct {
+		name    string
+		storage string
+		env     map[string]string
+		want    map[string]string
+	}{
+		{
+			name:    "Pass entry",
+			storage: "shellSafePass",
+			env:     map[string]string{"SAFE_USER": "username", "SAFE_PASS": "password"},
+			want:    map[string]string{"SAFE_USER": "jöhn \"the\" döe", "SAFE_PASS": password},
+		},
+		{
+			name:    "Keepass entry",
+			storage: "shellSafeKeepass",
+			env:     map[string]string{"SAFE_USER": "UserName", "SAFE_PASS": "Password"},
+			want:    map[string]string{"SAFE_USER": "jöhn 'the' döe", "SAFE_PASS": password},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Profile{name: "shell-safe", Storage: tt.storage, Path: "metachars", Env: tt.env}
+			env := environment.NewEnvironment()
+			if err := p.AddToEnvironment(&env); err != nil {
+				t.Fatalf("AddToEnvironment() returned error %v", err)
+			}
+			for key, want := range tt.want {
+				script := env.WriteStatements() + ";printf '%s' \"$" + key + "\""
+				got, err := exec.Command(shell, "-c", script).Output()
+				if err != nil {
+					t.Fatalf("Evaluating %q failed with %v", script, err)
+				}
+				if string(got) != want {
+					t.Errorf("%s = %q, want %q", key, string(got), want)
+				}
+			}
+		})
+	}
+}
+
 func TestProfile_GetDependencies(t *testing.T) {
 	type fields struct {
 		name      string
@@ -539,6 +611,8 @@ func TestProfile_Validate(t *testing.T) {
 		Path      string
 		ConstEnv  map[string]string
 		Env       map[string]string
+		Files     map[string]FileReference
+		Lists     map[string]ListOperation
 		DependsOn []string
 	}
 
@@ -604,6 +678,33 @@ func TestProfile_Validate(t *testing.T) {
 			},
 			want: []string{"depends on null which is not defined"},
 		},
+		{
+			name: "Variable set and used as list",
+			fields: fields{
+				name:     "the-profile",
+				Storage:  storageName,
+				Path:     "entry1",
+				ConstEnv: map[string]string{"PATH": "/usr/bin"},
+				Lists:    map[string]ListOperation{"PATH": {Prepend: []string{"/opt/bin"}}},
+			},
+			want: []string{"sets PATH and adds items to it as list"},
+		},
+		{
+			name: "Variable set and used as file",
+			fields: fields{
+				name:    "the-profile",
+				Storage: storageName,
+				Path:    "entry1",
+				Env:     map[string]string{"KUBECONFIG": "UserName"},
+				Files: map[string]FileReference{
+					"KUBECONFIG": {Attribute: "Password"},
+					"PATH":       {Attribute: "Password"},
+					"CA_CERT":    {Attribute: "Password"},
+				},
+				Lists: map[string]ListOperation{"PATH": {Prepend: []string{"/opt/bin"}}},
+			},
+			want: []string{"sets KUBECONFIG and writes a file for it", "sets PATH and writes a file for it"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -613,6 +714,8 @@ func TestProfile_Validate(t *testing.T) {
 				Path:      tt.fields.Path,
 				ConstEnv:  tt.fields.ConstEnv,
 				Env:       tt.fields.Env,
+				Files:     tt.fields.Files,
+				Lists:     tt.fields.Lists,
 				DependsOn: tt.fields.DependsOn,
 			}
 			got := p.Validate()
@@ -634,3 +737,241 @@ func getEmptyProfile(t *testing.T) Profile {
 		DependsOn: []string{},
 	}
 }
+
+func TestProfile_UnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    Profile
+		wantErr bool
+	}{
+		{
+			name: "Attribute names",
+			yaml: "storage: keepass\npath: entry1\nenv:\n  USER: UserName\n",
+			want: Profile{Storage: "keepass", Path: "entry1", Env: map[string]string{"USER": "UserName"}},
+		},
+		{
+			name: "References",
+			yaml: "storage: keepass\npath: entry1\nenv:\n  USER: UserName\n  TOKEN: {storage: pass, path: ci/token, attribute: password}\n  PASS: {attribute: Password}\n",
+			want: Profile{
+				Storage: "keepass",
+				Path:    "entry1",
+				Env:     map[string]string{"USER": "UserName"},
+				EnvRefs: map[string]EnvReference{
+					"TOKEN": {Storage: "pass", Path: "ci/token", Attribute: "password"},
+					"PASS":  {Attribute: "Password"},
+				},
+			},
+		},
+		{
+			name: "Lists",
+			yaml: "lists:\n  PATH:\n    prepend: [~/bin]\n  FLAGS:\n    append: [-v]\n    separator: \",\"\n",
+			want: Profile{Lists: map[string]ListOperation{
+				"PATH":  {Prepend: []string{"~/bin"}},
+				"FLAGS": {Append: []string{"-v"}, Separator: ","},
+			}},
+		},
+		{
+			name:    "Reference without attribute",
+			yaml:    "env:\n  TOKEN: {storage: pass, path: ci/token}\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Profile
+			err := yaml.Unmarshal([]byte(tt.yaml), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalYAML() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnmarshalYAML() got = %+v, want %+v", got, tt.want)
+			}
+
+			// writing the profile must keep the references
+			written, err := yaml.Marshal(got)
+			if err != nil {
+				t.Fatalf("MarshalYAML() error = %v", err)
+			}
+			var reread Profile
+			if err := yaml.Unmarshal(written, &reread); err != nil {
+				t.Fatalf("UnmarshalYAML() of the written profile error = %v", err)
+			}
+			if !reflect.DeepEqual(reread, tt.want) {
+				t.Errorf("MarshalYAML() wrote %s", written)
+			}
+		})
+	}
+}
+
+func TestProfile_AddToEnvironment_references(t *testing.T) {
+	goPassMock := new(internal.MockGoPass)
+	goPassMock.On("Get", context.Background(), "ci/token", "").Return(
+		secrets.NewAKVWithData("the-token", map[string][]string{}, "", false),
+		nil,
+	).Once()
+	_ = GetRegistry().AddStorage("referencesPass", &Pass{store: goPassMock})
+	_ = GetRegistry().AddStorage("referencesKeepass", &Keepass{
+		FilePath: internal.GetTestDataFile(t, "keepass.kdbx"),
+	})
+
+	p := &Profile{
+		name:    "references",
+		Storage: "referencesKeepass",
+		Path:    "entry1",
+		Env:     map[string]string{"DB_USER": "UserName"},
+		EnvRefs: map[string]EnvReference{
+			"DB_PASS":      {Attribute: "Password"},
+			"G1_USER":      {Path: "group1/g1e1", Attribute: "UserName"},
+			"CI_TOKEN":     {Storage: "referencesPass", Path: "ci/token", Attribute: "password"},
+			"CI_TOKEN_TOO": {Storage: "referencesPass", Path: "ci/token", Attribute: "password"},
+		},
+	}
+	env := environment.NewEnvironment()
+	helper.GetInput().Inputs = []string{"1234"}
+	if err := p.AddToEnvironment(&env); err != nil {
+		t.Fatalf("AddToEnvironment() returned error %v", err)
+	}
+	want := map[string]string{
+		"DB_USER":      "user1",
+		"DB_PASS":      "pass1",
+		"G1_USER":      "g1e1-user",
+		"CI_TOKEN":     "the-token",
+		"CI_TOKEN_TOO": "the-token",
+	}
+	for key, wantValue := range want {
+		if got, _ := env.Lookup(key); got != wantValue {
+			t.Errorf("%s = %q, want %q", key, got, wantValue)
+		}
+	}
+	// the entry is loaded once for both variables
+	goPassMock.AssertNumberOfCalls(t, "Get", 1)
+
+	if err := p.RemoveFromEnvironment(&env); err != nil {
+		t.Fatalf("RemoveFromEnvironment() returned error %v", err)
+	}
+	for key := range want {
+		if _, isSet := env.Lookup(key); isSet {
+			t.Errorf("%s is still set after RemoveFromEnvironment()", key)
+		}
+	}
+}
+
+func TestProfile_AddToEnvironment_files(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	_ = GetRegistry().AddStorage("filesKeepass", &Keepass{
+		FilePath: internal.GetTestDataFile(t, "keepass.kdbx"),
+	})
+	p := &Profile{
+		name:    "files",
+		Storage: "filesKeepass",
+		Path:    "entry1",
+		Files: map[string]FileReference{
+			"PASSWORD_FILE": {Attribute: "Password"},
+			"USER_FILE":     {Path: "group1/g1e1", Attribute: "UserName", Mode: 0640},
+		},
+	}
+	env := environment.NewEnvironment()
+	helper.GetInput().Inputs = []string{"1234"}
+	if err := p.AddToEnvironment(&env); err != nil {
+		t.Fatalf("AddToEnvironment() returned error %v", err)
+	}
+
+	tests := []struct {
+		key         string
+		wantContent string
+		wantMode    os.FileMode
+	}{
+		{key: "PASSWORD_FILE", wantContent: "pass1", wantMode: 0600},
+		{key: "USER_FILE", wantContent: "g1e1-user", wantMode: 0640},
+	}
+	var paths []string
+	for _, tt := range tests {
+		path, _ := env.Lookup(tt.key)
+		paths = append(paths, path)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s does not point to a file: %v", tt.key, err)
+		}
+		if string(content) != tt.wantContent {
+			t.Errorf("Content of %s = %q, want %q", tt.key, content, tt.wantContent)
+		}
+		info, _ := os.Stat(path)
+		if info.Mode().Perm() != tt.wantMode {
+			t.Errorf("Mode of %s = %o, want %o", tt.key, info.Mode().Perm(), tt.wantMode)
+		}
+	}
+
+	if err := p.RemoveFromEnvironment(&env); err != nil {
+		t.Fatalf("RemoveFromEnvironment() returned error %v", err)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("RemoveFromEnvironment() did not remove %s", path)
+		}
+	}
+}
+
+func TestProfile_AddToEnvironment_lists(t *testing.T) {
+	t.Setenv("HOME", "/home/user")
+	p := &Profile{
+		name: "lists",
+		Lists: map[string]ListOperation{
+			"PATH":  {Prepend: []string{"~/bin", "/opt/tool/bin"}, Append: []string{"/opt/fallback"}},
+			"FLAGS": {Append: []string{"-v"}, Separator: ","},
+		},
+	}
+	env := environment.NewEnvironment()
+	_ = env.Set("PATH", "/usr/bin:/bin")
+	if err := p.AddToEnvironment(&env); err != nil {
+		t.Fatalf("AddToEnvironment() returned error %v", err)
+	}
+	want := map[string]string{
+		"PATH":  "/home/user/bin:/opt/tool/bin:/usr/bin:/bin:/opt/fallback",
+		"FLAGS": "-v",
+	}
+	for key, wantValue := range want {
+		if got, _ := env.Lookup(key); got != wantValue {
+			t.Errorf("%s = %q, want %q", key, got, wantValue)
+		}
+	}
+
+	if err := p.RemoveFromEnvironment(&env); err != nil {
+		t.Fatalf("RemoveFromEnvironment() returned error %v", err)
+	}
+	if got, _ := env.Lookup("PATH"); got != "/usr/bin:/bin" {
+		t.Errorf("PATH = %q after RemoveFromEnvironment(), want %q", got, "/usr/bin:/bin")
+	}
+	if _, isSet := env.Lookup("FLAGS"); isSet {
+		t.Error("FLAGS is still set after RemoveFromEnvironment()")
+	}
+}
+
+func TestFileMode_UnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    FileMode
+		wantErr bool
+	}{
+		{name: "Octal number", yaml: "0640", want: 0640},
+		{name: "Octal string", yaml: `"0400"`, want: 0400},
+		{name: "Invalid string", yaml: `"rw"`, wantErr: true},
+		{name: "Not only permissions", yaml: "04755", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got FileMode
+			err := yaml.Unmarshal([]byte(tt.yaml), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalYAML() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want && !tt.wantErr {
+				t.Errorf("UnmarshalYAML() got = %o, want %o", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,6 +9,8 @@ type Registry struct {
 	storages         map[string]StorageAdapter
 	profiles         map[string]Profile
 	directoryMapping map[string][]string
+	// exactDirectoryMappings contains the paths of directory mappings which do not apply to subdirectories
+	exactDirectoryMappings map[string]bool
 }
 
 var instance *Registry
@@ -23,9 +25,10 @@ func GetRegistry() *Registry {
 
 func newRegistry() *Registry {
 	return &Registry{
-		storages:         map[string]StorageAdapter{},
-		profiles:         map[string]Profile{},
-		directoryMapping: map[string][]string{},
+		storages:               map[string]StorageAdapter{},
+		profiles:               map[string]Profile{},
+		directoryMapping:       map[string][]string{},
+		exactDirectoryMappings: map[string]bool{},
 	}
 }
 
@@ -64,6 +67,20 @@ func (r *Registry) AddDirectoryMapping(path string, profiles []string) error {
 		return errors.New("profiles cannot be empty")
 	}
 	r.directoryMapping[path] = profiles
+	delete(r.exactDirectoryMappings, path)
+	return nil
+}
+
+// AddExactDirectoryMapping adds a directory mapping like AddDirectoryMapping, but
+// the mapping only applies to the directory itself and not to its subdirectories.
+func (r *Registry) AddExactDirectoryMapping(path string, profiles []string) error {
+	if err := r.AddDirectoryMapping(path, profiles); err != nil {
+		return err
+	}
+	if r.exactDirectoryMappings == nil {
+		r.exactDirectoryMappings = map[string]bool{}
+	}
+	r.exactDirectoryMappings[path] = true
 	return nil
 }
 
@@ -93,17 +110,46 @@ func (r *Registry) GetStorage(name string) (*StorageAdapter, error) {
 	return &storage, nil
 }
 
-// GetDirectoryMapping retrieves the profile names mapped to the given path.
-// Will return an error if given path is empty or unknown to the registry
+// GetDirectoryMapping retrieves the profile names mapped to the given path, see
+// FindDirectoryMapping for how the mapping is chosen. Will return an error if
+// given path is empty or no mapping applies to it.
 func (r *Registry) GetDirectoryMapping(path string) ([]string, error) {
+	match, err := r.FindDirectoryMapping(path)
+	if err != nil {
+		return nil, err
+	}
+	return match.Profiles, nil
+}
+
+// FindDirectoryMapping finds the directory mapping which applies to the given
+// path. A mapping applies to the mapped directory and its subdirectories (unless
+// it is exact), mapped paths can be glob patterns like /code/*/infra. If several
+// mappings apply, the one of the nearest directory wins. Will return an error if
+// given path is empty or no mapping applies to it.
+func (r *Registry) FindDirectoryMapping(path string) (*DirectoryMappingMatch, error) {
 	if path == "" {
 		return nil, errors.New("path cannot be empty")
 	}
-	profiles, exists := r.directoryMapping[path]
-	if !exists {
+	matches := r.ExplainDirectoryMapping(path)
+	if len(matches) == 0 || !matches[0].Matched {
 		return nil, errors.Newf("directory mapping for path %s does not exist", path)
 	}
-	return profiles, nil
+	return &matches[0], nil
+}
+
+// ExplainDirectoryMapping checks every directory mapping against the given path.
+// The result is sorted by precedence, if the first entry matched, it is the
+// mapping FindDirectoryMapping returns.
+func (r *Registry) ExplainDirectoryMapping(path string) []DirectoryMappingMatch {
+	var matches []DirectoryMappingMatch
+	if path == "" {
+		return matches
+	}
+	for mappedPath, profiles := range r.directoryMapping {
+		matches = append(matches, matchDirectoryMapping(mappedPath, profiles, r.exactDirectoryMappings[mappedPath], path))
+	}
+	sortDirectoryMappingMatches(matches)
+	return matches
 }
 
 // HasStorage checks if the registry knows about a storage with this name
@@ -118,10 +164,10 @@ func (r *Registry) HasProfile(name string) bool {
 	return exists
 }
 
-// HasDirectoryMapping checks if the registry knows about a directory mapping for this path
+// HasDirectoryMapping checks if a directory mapping applies to this path
 func (r *Registry) HasDirectoryMapping(path string) bool {
-	_, exists := r.directoryMapping[path]
-	return exists
+	_, err := r.FindDirectoryMapping(path)
+	return err == nil
 }
 
 // GetAllStorages returns all storages known to the registry
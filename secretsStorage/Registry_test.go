@@ -896,6 +896,80 @@ func TestRegistry_GetDirectoryMapping(t *testing.T) {
 	}
 }
 
+func TestRegistry_FindDirectoryMapping(t *testing.T) {
+	r := &Registry{
+		storages: map[string]StorageAdapter{},
+		profiles: map[string]Profile{},
+		directoryMapping: map[string][]string{
+			"/code/terraform":            {"terraform"},
+			"/code/terraform/modules":    {"modules"},
+			"/code/*/infra":              {"infra"},
+			"/code/special/infra":        {"special"},
+			"/code/exact/":               {"exact"},
+			"/code/[invalid":             {"invalid"},
+			"/home/user/project/nested/": {"nested"},
+		},
+	}
+	_ = r.AddExactDirectoryMapping("/code/exact/", []string{"exact"})
+	tests := []struct {
+		name        string
+		path        string
+		want        []string
+		wantMatched string
+		wantErr     bool
+	}{
+		{name: "Empty path", path: "", wantErr: true},
+		{name: "Mapped directory", path: "/code/terraform", want: []string{"terraform"}, wantMatched: "/code/terraform"},
+		{name: "Subdirectory", path: "/code/terraform/env/prod", want: []string{"terraform"}, wantMatched: "/code/terraform"},
+		{name: "Nearest mapping wins", path: "/code/terraform/modules/vpc", want: []string{"modules"}, wantMatched: "/code/terraform/modules"},
+		{name: "Similar prefix is no subdirectory", path: "/code/terraform-old", wantErr: true},
+		{name: "Glob pattern", path: "/code/api/infra", want: []string{"infra"}, wantMatched: "/code/api/infra"},
+		{name: "Subdirectory of glob pattern", path: "/code/api/infra/vpc", want: []string{"infra"}, wantMatched: "/code/api/infra"},
+		{name: "Glob does not cross directories", path: "/code/api/v2/infra", wantErr: true},
+		{name: "Literal path beats glob pattern", path: "/code/special/infra", want: []string{"special"}, wantMatched: "/code/special/infra"},
+		{name: "Exact mapping", path: "/code/exact", want: []string{"exact"}, wantMatched: "/code/exact"},
+		{name: "Exact mapping ignores subdirectories", path: "/code/exact/sub", wantErr: true},
+		{name: "Trailing slash of mapping", path: "/home/user/project/nested", want: []string{"nested"}, wantMatched: "/home/user/project/nested"},
+		{name: "Parent of mapping", path: "/home/user/project", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.FindDirectoryMapping(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FindDirectoryMapping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !internal.AssertStringSliceEqual(t, tt.want, got.Profiles) {
+				t.Errorf("FindDirectoryMapping() profiles = %v, want %v", got.Profiles, tt.want)
+			}
+			if got.MatchedDirectory != tt.wantMatched {
+				t.Errorf("FindDirectoryMapping() matched directory = %v, want %v", got.MatchedDirectory, tt.wantMatched)
+			}
+		})
+	}
+}
+
+func TestRegistry_ExplainDirectoryMapping(t *testing.T) {
+	r := &Registry{
+		directoryMapping: map[string][]string{
+			"/code/terraform": {"terraform"},
+			"/code/other":     {"other"},
+		},
+	}
+	got := r.ExplainDirectoryMapping("/code/terraform/modules")
+	if len(got) != 2 {
+		t.Fatalf("ExplainDirectoryMapping() returned %d entries, want 2", len(got))
+	}
+	if !got[0].Matched || got[0].Path != "/code/terraform" || got[0].Reason == "" {
+		t.Errorf("ExplainDirectoryMapping() first entry = %+v, want the matching /code/terraform", got[0])
+	}
+	if got[1].Matched || got[1].Reason == "" {
+		t.Errorf("ExplainDirectoryMapping() second entry = %+v, want an explained mismatch", got[1])
+	}
+}
+
 func TestRegistry_GetDirectoryMappedPaths(t *testing.T) {
 	type fields struct {
 		storages         map[string]StorageAdapter
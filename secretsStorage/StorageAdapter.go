@@ -1,7 +1,14 @@
 package secretsStorage
 
 import (
+	"encoding/json"
+	"envManager/storagePlugin"
+	"fmt"
 	"gopkg.in/errgo.v2/fmt/errors"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
 )
 
 // StorageAdapter provides methods to interact with a secrets secretsStorage (e.g. keepass)
@@ -20,51 +27,173 @@ type StorageAdapter interface {
 	GetDefaultConfig() map[string]string
 }
 
-// CreateStorageAdapter is a factory method which creates a specific storage adapter determined by data["type"] and calls
-// StorageAdapter.Validate on the created instance. Should StorageAdapter.Validate return an error, it is handed through
-// to the caller of CreateStorageAdapter
+// LockableStorageAdapter is a StorageAdapter which must be unlocked with a secret (e.g. a password) before entries can
+// be retrieved. Lockable storage adapters can be kept unlocked by the agent.
+type LockableStorageAdapter interface {
+	StorageAdapter
+	//IsLocked indicates if the storage adapter must be unlocked before retrieving entries
+	IsLocked() bool
+	//GetUnlockPrompt returns the prompt to show when asking the user for the secret. It returns an empty string if no
+	//secret is required, Unlock is called with an empty secret then.
+	GetUnlockPrompt() string
+	//PromptSecret returns the secret to unlock the storage adapter, e.g. by asking the user
+	PromptSecret() (string, error)
+	//Unlock unlocks the storage adapter with the secret. It returns an error if the secret is wrong.
+	Unlock(secret string) error
+	//Lock forgets everything learned by unlocking the storage adapter
+	Lock()
+}
+
+// EntryLister is a StorageAdapter which can list its entries, e.g. for completion
+type EntryLister interface {
+	StorageAdapter
+	//ListEntries returns the sorted paths of all entries starting with prefix. The paths can be passed to GetEntry.
+	ListEntries(prefix string) ([]string, error)
+}
+
+// EntryWriter is a StorageAdapter which can create and update its entries
+type EntryWriter interface {
+	StorageAdapter
+	//SetEntry sets the attributes of entry on the entry addressed by key. The entry is created if it does not exist,
+	//attributes not contained in entry are kept.
+	SetEntry(key string, entry *Entry) error
+}
+
+// RemoteStorageAdapter is a StorageAdapter whose Validate contacts a server. It is validated offline when it is
+// created, as every invocation of envManager creates all storage adapters.
+type RemoteStorageAdapter interface {
+	StorageAdapter
+	//ValidateConfig verifies the config without contacting the server
+	ValidateConfig() error
+}
+
+// StorageAdapterFactory creates a storage adapter with the name from its config. The storage adapter is validated by
+// CreateStorageAdapter, so the factory only has to report invalid config values it cannot store.
+type StorageAdapterFactory func(name string, config Storage) (StorageAdapter, error)
+
+// storageAdapterType is a registered storage adapter type
+type storageAdapterType struct {
+	factory       StorageAdapterFactory
+	defaultConfig map[string]string
+}
+
+// storageAdapterTypes contains the registered storage adapter types by their identifier
+var storageAdapterTypes = map[string]storageAdapterType{}
+
+// RegisterStorageAdapterType makes a storage adapter type available under the identifier used in the config files.
+// The defaultConfig is used to initialize a storage adapter section in the config file. It is meant to be called from
+// the init function of the package of the storage adapter and panics if the identifier is already registered.
+func RegisterStorageAdapterType(identifier string, factory StorageAdapterFactory, defaultConfig map[string]string) {
+	if factory == nil {
+		panic("secretsStorage: the factory of storage adapter type " + identifier + " is nil")
+	}
+	if _, exists := storageAdapterTypes[identifier]; exists {
+		panic("secretsStorage: storage adapter type " + identifier + " is registered twice")
+	}
+	storageAdapterTypes[identifier] = storageAdapterType{factory: factory, defaultConfig: defaultConfig}
+}
+
+// CreateStorageAdapter is a factory method which creates a specific storage adapter determined by config.StorageType
+// and calls StorageAdapter.Validate (RemoteStorageAdapter.ValidateConfig for remote storages) on the created instance.
+// Should the validation return an error, it is handed through to the caller of CreateStorageAdapter. If an agent is
+// running (see AgentSocketVariableName), lockable storage adapters retrieve their entries through the agent.
 func CreateStorageAdapter(name string, config Storage) (StorageAdapter, error) {
-	var storage StorageAdapter
-	switch config.StorageType {
-	case KeepassTypeIdentifier:
-		storage = &Keepass{
-			Name:     name,
-			FilePath: config.Config["path"],
+	storage, err := createStorageAdapter(name, config)
+	if err != nil {
+		return nil, err
+	}
+	if socketPath := os.Getenv(AgentSocketVariableName); socketPath != "" {
+		if lockable, isLockable := storage.(LockableStorageAdapter); isLockable {
+			return &agentStorageAdapter{
+				LockableStorageAdapter: lockable,
+				name:                   name,
+				config:                 config,
+				client:                 NewAgentClient(socketPath),
+			}, nil
 		}
-	case PassTypeIdentifier:
-		storage = &Pass{
-			Name:   name,
-			Prefix: config.Config["prefix"],
+	}
+	return storage, nil
+}
+
+// createStorageAdapter creates and validates the storage adapter like CreateStorageAdapter, but never proxies it
+// through the agent. Storage types which are not registered are looked up as plugin.
+func createStorageAdapter(name string, config Storage) (StorageAdapter, error) {
+	var storage StorageAdapter
+	var err error
+	if adapterType, registered := storageAdapterTypes[config.StorageType]; registered {
+		storage, err = adapterType.factory(name, config)
+	} else {
+		storage, err = newPlugin(name, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if remote, isRemote := storage.(RemoteStorageAdapter); isRemote {
+		if err := remote.ValidateConfig(); err != nil {
+			return nil, err
 		}
-	default:
-		return nil, errors.Newf("Unknown storage type %s", config.StorageType)
+		return storage, nil
 	}
-	err, _ := storage.Validate()
+	err, _ = storage.Validate()
 	if err != nil {
 		return nil, err
 	}
 	return storage, err
 }
 
-// GetStorageAdapterTypes returns a list of type identifiers for storage adapters
+// GetStorageAdapterTypes returns a list of type identifiers for storage adapters, including the plugins on the PATH
 func GetStorageAdapterTypes() []string {
-	return []string{
-		KeepassTypeIdentifier,
-		PassTypeIdentifier,
+	types := slices.Sorted(maps.Keys(storageAdapterTypes))
+	pluginTypes := slices.Sorted(maps.Keys(storagePlugin.FindPlugins()))
+	for _, pluginType := range pluginTypes {
+		// registered storage adapters cannot be replaced by plugins
+		if !slices.Contains(types, pluginType) {
+			types = append(types, pluginType)
+		}
 	}
+	return types
 }
 
 // GetStorageAdapterDefaultConfig returns the default config for a given storage type
 func GetStorageAdapterDefaultConfig(storageType string) (map[string]string, error) {
-	var storage StorageAdapter
-	switch storageType {
-	case KeepassTypeIdentifier:
-		storage = &Keepass{}
-	case PassTypeIdentifier:
-		storage = &Pass{}
-	default:
-		return nil, errors.Newf("Unknown storage type %s", storageType)
+	if adapterType, registered := storageAdapterTypes[storageType]; registered {
+		// the caller may modify the config
+		return maps.Clone(adapterType.defaultConfig), nil
+	}
+	plugin, err := newPlugin("", Storage{StorageType: storageType})
+	if err != nil {
+		return nil, err
 	}
+	return plugin.GetDefaultConfig(), nil
+}
 
-	return storage.GetDefaultConfig(), nil
+// parseBoolOption reads an optional boolean option of a storage config. A missing or empty option is false.
+func parseBoolOption(config map[string]string, option string) (bool, error) {
+	value := config[option]
+	if value == "" {
+		return false, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.Newf("Invalid value %s for option %s, expected true or false", value, option)
+	}
+	return parsed, nil
+}
+
+// formatJSONValue converts a decoded JSON value into an attribute value. Nested values are encoded as JSON again.
+func formatJSONValue(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	case json.Number, bool:
+		return fmt.Sprint(typed)
+	default:
+		encoded, err := json.Marshal(typed)
+		if err != nil {
+			return fmt.Sprint(typed)
+		}
+		return string(encoded)
+	}
 }
@@ -2,6 +2,7 @@ package secretsStorage
 
 import (
 	"reflect"
+	"slices"
 	"testing"
 )
 
@@ -44,3 +45,49 @@ func TestCreateStorageAdapter(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterStorageAdapterType(t *testing.T) {
+	const identifier = "registered"
+	factory := func(name string, config Storage) (StorageAdapter, error) {
+		return &Dotenv{Name: name, FilePath: config.Config["path"]}, nil
+	}
+	RegisterStorageAdapterType(identifier, factory, map[string]string{"path": ""})
+	t.Cleanup(func() {
+		delete(storageAdapterTypes, identifier)
+	})
+
+	storage, err := CreateStorageAdapter("test", Storage{StorageType: identifier, Config: map[string]string{"path": t.TempDir()}})
+	if err != nil {
+		t.Fatalf("CreateStorageAdapter() error = %v", err)
+	}
+	if storage.(*Dotenv).Name != "test" {
+		t.Errorf("CreateStorageAdapter() did not use the factory")
+	}
+	// the storage adapter is validated after the factory created it
+	if _, err := CreateStorageAdapter("test", Storage{StorageType: identifier, Config: map[string]string{"path": "/nonexistent"}}); err == nil {
+		t.Errorf("CreateStorageAdapter() did not validate the storage adapter")
+	}
+
+	config, err := GetStorageAdapterDefaultConfig(identifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config["path"] = "modified"
+	if storageAdapterTypes[identifier].defaultConfig["path"] != "" {
+		t.Errorf("GetStorageAdapterDefaultConfig() returned the registered default config")
+	}
+
+	types := GetStorageAdapterTypes()
+	for _, builtIn := range []string{identifier, KeepassTypeIdentifier, PassTypeIdentifier} {
+		if !slices.Contains(types, builtIn) {
+			t.Errorf("GetStorageAdapterTypes() got %v, want it to contain %s", types, builtIn)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("RegisterStorageAdapterType() did not panic for a duplicate identifier")
+		}
+	}()
+	RegisterStorageAdapterType(identifier, factory, nil)
+}
@@ -0,0 +1,149 @@
+package secretsStorage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"envManager/helper"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// TrustStatus tells if a config file may be merged into the configuration
+type TrustStatus int
+
+const (
+	// TrustStatusUntrusted is the status of files which were never allowed or denied
+	TrustStatusUntrusted TrustStatus = iota
+	// TrustStatusTrusted is the status of allowed files which were not modified since
+	TrustStatusTrusted
+	// TrustStatusModified is the status of allowed files which were modified since
+	TrustStatusModified
+	// TrustStatusDenied is the status of denied files
+	TrustStatusDenied
+)
+
+// String returns a human-readable description of the status
+func (s TrustStatus) String() string {
+	switch s {
+	case TrustStatusTrusted:
+		return "allowed"
+	case TrustStatusModified:
+		return "modified since it was allowed"
+	case TrustStatusDenied:
+		return "denied"
+	default:
+		return "not allowed"
+	}
+}
+
+// TrustStore records which config files the user allowed (by the hash of their content) or denied. Discovered config
+// files are only merged if they are allowed and were not modified since.
+type TrustStore struct {
+	path string
+	//Allowed maps the absolute path of allowed files to the sha256 hash of their content
+	Allowed map[string]string `yaml:"allowed,omitempty"`
+	//Denied contains the absolute paths of denied files
+	Denied []string `yaml:"denied,omitempty"`
+}
+
+// GetDefaultTrustStorePath returns the path of the trust store in the user's config directory
+func GetDefaultTrustStorePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "envManager", "trust.yml"), nil
+}
+
+// LoadTrustStore loads the trust store at path. A missing file is treated as an empty trust store, it is created by
+// Save.
+func LoadTrustStore(path string) (*TrustStore, error) {
+	store := &TrustStore{path: path, Allowed: map[string]string{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, store); err != nil {
+		return nil, err
+	}
+	if store.Allowed == nil {
+		store.Allowed = map[string]string{}
+	}
+	return store, nil
+}
+
+// Save writes the trust store to its path. The directory is created if needed.
+func (t *TrustStore) Save() error {
+	data, err := yaml.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(t.path, data, 0600)
+}
+
+// Allow trusts the current content of the file. Changing the file revokes the trust.
+func (t *TrustStore) Allow(file string) error {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	hash, err := hashFile(file)
+	if err != nil {
+		return err
+	}
+	t.Denied = helper.SliceStringRemove(file, t.Denied)
+	t.Allowed[file] = hash
+	return nil
+}
+
+// Deny revokes the trust of the file. Denied files are skipped without further notice.
+func (t *TrustStore) Deny(file string) error {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	delete(t.Allowed, file)
+	t.Denied = append(helper.SliceStringRemove(file, t.Denied), file)
+	return nil
+}
+
+// GetStatus checks if the file may be merged into the configuration
+func (t *TrustStore) GetStatus(file string) (TrustStatus, error) {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return TrustStatusUntrusted, err
+	}
+	if slices.Contains(t.Denied, file) {
+		return TrustStatusDenied, nil
+	}
+	allowedHash, exists := t.Allowed[file]
+	if !exists {
+		return TrustStatusUntrusted, nil
+	}
+	hash, err := hashFile(file)
+	if err != nil {
+		return TrustStatusUntrusted, err
+	}
+	if hash != allowedHash {
+		return TrustStatusModified, nil
+	}
+	return TrustStatusTrusted, nil
+}
+
+// hashFile returns the hex encoded sha256 hash of the file's content
+func hashFile(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,73 @@
+package secretsStorage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTrustStore(t *testing.T) {
+	dir := t.TempDir()
+	storePath := filepath.Join(dir, "config", "trust.yml")
+	configFile := filepath.Join(dir, ".envManager.yml")
+	if err := os.WriteFile(configFile, []byte("directoryMapping: {}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	assertStatus := func(t *testing.T, store *TrustStore, want TrustStatus) {
+		t.Helper()
+		got, err := store.GetStatus(configFile)
+		if err != nil {
+			t.Fatalf("GetStatus() returned error %v", err)
+		}
+		if got != want {
+			t.Errorf("GetStatus() = %v, want %v", got, want)
+		}
+	}
+
+	store, err := LoadTrustStore(storePath)
+	if err != nil {
+		t.Fatalf("LoadTrustStore() of a missing file returned error %v", err)
+	}
+	assertStatus(t, store, TrustStatusUntrusted)
+
+	if err := store.Allow(configFile); err != nil {
+		t.Fatalf("Allow() returned error %v", err)
+	}
+	assertStatus(t, store, TrustStatusTrusted)
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save() returned error %v", err)
+	}
+
+	// the trust survives reloading the store
+	store, err = LoadTrustStore(storePath)
+	if err != nil {
+		t.Fatalf("LoadTrustStore() returned error %v", err)
+	}
+	assertStatus(t, store, TrustStatusTrusted)
+
+	if err := os.WriteFile(configFile, []byte("directoryMapping: {/: [evil]}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, store, TrustStatusModified)
+
+	if err := store.Deny(configFile); err != nil {
+		t.Fatalf("Deny() returned error %v", err)
+	}
+	assertStatus(t, store, TrustStatusDenied)
+
+	// allowing a denied file again trusts its current content
+	if err := store.Allow(configFile); err != nil {
+		t.Fatalf("Allow() returned error %v", err)
+	}
+	assertStatus(t, store, TrustStatusTrusted)
+}
+
+func TestLoadTrustStore_invalidFile(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "trust.yml")
+	if err := os.WriteFile(storePath, []byte("allowed: [not, a, map]"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTrustStore(storePath); err == nil {
+		t.Error("LoadTrustStore() got no error but wanted one")
+	}
+}
@@ -0,0 +1,290 @@
+package secretsStorage
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/errgo.v2/fmt/errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const VaultTypeIdentifier = "vault"
+
+func init() {
+	RegisterStorageAdapterType(VaultTypeIdentifier, newVault, (&Vault{}).GetDefaultConfig())
+}
+
+// VaultMetadataPrefix is the prefix of the attributes containing the metadata of a secret, e.g. metadata:version
+const VaultMetadataPrefix = "metadata:"
+
+// vaultDefaultAddress is used if neither the config nor VAULT_ADDR contain an address, like the vault CLI does
+const vaultDefaultAddress = "https://127.0.0.1:8200"
+
+// vaultDefaultMount is the mount of the KV secrets engine if the config contains none, like in a new dev server
+const vaultDefaultMount = "secret"
+
+// Vault reads secrets from a KV secrets engine of HashiCorp Vault. The key of an entry is the path of the secret
+// inside the mount, the data of the secret are the attributes of the entry. For KV version 2, the metadata of the
+// secret are added as attributes with the VaultMetadataPrefix.
+type Vault struct {
+	Name    string
+	Address string
+	Mount   string
+	//KVVersion is the version of the KV secrets engine, 1 or 2
+	KVVersion int
+	Namespace string
+	//TokenFile contains the token. If it is empty, VAULT_TOKEN or ~/.vault-token is used.
+	TokenFile string
+	client    *http.Client
+}
+
+// vaultResponse is the part of the responses of the Vault HTTP API used by the adapter
+type vaultResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []string               `json:"errors"`
+}
+
+// newVault creates a Vault storage adapter from its config
+func newVault(name string, config Storage) (StorageAdapter, error) {
+	kvVersion, err := parseVaultKVVersion(config.Config)
+	if err != nil {
+		return nil, err
+	}
+	return &Vault{
+		Name:      name,
+		Address:   getVaultAddress(config.Config["address"]),
+		Mount:     cmp.Or(config.Config["mount"], vaultDefaultMount),
+		KVVersion: kvVersion,
+		Namespace: config.Config["namespace"],
+		TokenFile: config.Config["tokenFile"],
+	}, nil
+}
+
+func (v *Vault) GetEntry(key string) (*Entry, error) {
+	key = strings.Trim(key, "/")
+	mount := escapeVaultPath(strings.Trim(v.Mount, "/"))
+	secretPath := mount + "/" + escapeVaultPath(key)
+	if v.KVVersion == 2 {
+		secretPath = mount + "/data/" + escapeVaultPath(key)
+	}
+	response, statusCode, err := v.request(secretPath)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode == http.StatusNotFound {
+		return nil, errors.Newf("Could not find entry %s in %s", key, v.Name)
+	}
+	if statusCode != http.StatusOK {
+		return nil, response.getError(statusCode)
+	}
+
+	data := response.Data
+	var metadata map[string]interface{}
+	if v.KVVersion == 2 {
+		data, _ = response.Data["data"].(map[string]interface{})
+		metadata, _ = response.Data["metadata"].(map[string]interface{})
+	}
+
+	entry := NewEntry()
+	for name, value := range data {
+		if err := entry.SetAttribute(name, formatJSONValue(value)); err != nil {
+			return nil, err
+		}
+	}
+	for name, value := range metadata {
+		if err := entry.SetAttribute(VaultMetadataPrefix+name, formatJSONValue(value)); err != nil {
+			return nil, err
+		}
+	}
+	return &entry, nil
+}
+
+func (v *Vault) IsCaseSensitive() bool {
+	return true
+}
+
+func (v *Vault) Validate() (error, []string) {
+	var out []string
+	validationFailed := false
+
+	if v.KVVersion != 1 && v.KVVersion != 2 {
+		validationFailed = true
+		out = append(out, fmt.Sprintf("Configured KV version %d is not supported, use 1 or 2", v.KVVersion))
+	}
+
+	// sys/health answers with different status codes depending on the state, any answer means the server is reachable
+	_, _, err := v.request("sys/health")
+	reachable := err == nil
+	if !reachable {
+		validationFailed = true
+	}
+	out = append(out, fmt.Sprintf("Configured address is %s\nServer is reachable: %t", v.Address, reachable))
+
+	tokenSource := v.getTokenSource()
+	tokenValid := false
+	if reachable {
+		_, statusCode, err := v.request("auth/token/lookup-self")
+		tokenValid = err == nil && statusCode == http.StatusOK
+	}
+	if !tokenValid {
+		validationFailed = true
+	}
+	out = append(out, fmt.Sprintf("Token is read from %s\nToken is valid: %t", tokenSource, tokenValid))
+
+	if validationFailed {
+		return errors.Newf("Validation of %s failed. Run debug storage %s to check it in detail", v.Name, v.Name), out
+	}
+	return nil, out
+}
+
+// ValidateConfig checks the address and the KV version without contacting Vault
+func (v *Vault) ValidateConfig() error {
+	if v.KVVersion != 1 && v.KVVersion != 2 {
+		return errors.Newf("Configured KV version %d of %s is not supported, use 1 or 2", v.KVVersion, v.Name)
+	}
+	if _, err := url.ParseRequestURI(v.Address); err != nil {
+		return errors.Newf("Configured address %s of %s is invalid: %s", v.Address, v.Name, err)
+	}
+	return nil
+}
+
+func (v *Vault) GetDefaultConfig() map[string]string {
+	return map[string]string{
+		"address":   "",
+		"mount":     vaultDefaultMount,
+		"kvVersion": "2",
+		"namespace": "",
+		"tokenFile": "",
+	}
+}
+
+// request sends a GET request to the Vault HTTP API. Returns the decoded response and the status code, an error is
+// only returned if the server could not be reached or answered with something else than JSON.
+func (v *Vault) request(apiPath string) (*vaultResponse, int, error) {
+	request, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(v.Address, "/")+"/v1/"+apiPath, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	token, err := v.getToken()
+	if err != nil {
+		return nil, 0, err
+	}
+	request.Header.Set("X-Vault-Token", token)
+	request.Header.Set("X-Vault-Request", "true")
+	if v.Namespace != "" {
+		request.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+
+	client := v.client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, 0, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, response.StatusCode, err
+	}
+	var decoded vaultResponse
+	if len(bytes.TrimSpace(body)) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&decoded); err != nil {
+			return nil, response.StatusCode, fmt.Errorf("unexpected response from %s: %w", v.Address, err)
+		}
+	}
+	return &decoded, response.StatusCode, nil
+}
+
+// getToken reads the token from the configured token file, VAULT_TOKEN or ~/.vault-token
+func (v *Vault) getToken() (string, error) {
+	if v.TokenFile == "" {
+		if token, exists := os.LookupEnv("VAULT_TOKEN"); exists {
+			return token, nil
+		}
+	}
+	tokenFile, err := v.getTokenFile()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the vault token: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// getTokenFile returns the configured token file or the one written by vault login
+func (v *Vault) getTokenFile() (string, error) {
+	if v.TokenFile != "" {
+		return v.TokenFile, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".vault-token"), nil
+}
+
+// getTokenSource describes where the token is read from
+func (v *Vault) getTokenSource() string {
+	if _, exists := os.LookupEnv("VAULT_TOKEN"); exists && v.TokenFile == "" {
+		return "$VAULT_TOKEN"
+	}
+	tokenFile, err := v.getTokenFile()
+	if err != nil {
+		return err.Error()
+	}
+	return tokenFile
+}
+
+// getError converts the errors reported by Vault into an error
+func (r *vaultResponse) getError(statusCode int) error {
+	if len(r.Errors) == 0 {
+		return errors.Newf("Vault answered with status %d", statusCode)
+	}
+	return errors.Newf("Vault answered with status %d: %s", statusCode, strings.Join(r.Errors, ", "))
+}
+
+// getVaultAddress returns the configured address, VAULT_ADDR or the default address of Vault
+func getVaultAddress(address string) string {
+	if address != "" {
+		return address
+	}
+	if envAddress := os.Getenv("VAULT_ADDR"); envAddress != "" {
+		return envAddress
+	}
+	return vaultDefaultAddress
+}
+
+// parseVaultKVVersion parses the kvVersion option, an empty option means version 2
+func parseVaultKVVersion(config map[string]string) (int, error) {
+	switch config["kvVersion"] {
+	case "", "2":
+		return 2, nil
+	case "1":
+		return 1, nil
+	default:
+		return 0, errors.Newf("Invalid value %s for option kvVersion, use 1 or 2", config["kvVersion"])
+	}
+}
+
+// escapeVaultPath escapes the segments of a slash separated path for the URL
+func escapeVaultPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
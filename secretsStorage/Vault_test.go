@@ -0,0 +1,200 @@
+package secretsStorage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const vaultTestToken = "test-token"
+
+// startTestVault starts a stand-in for the Vault HTTP API with a KV v2 mount "secret" and a KV v1 mount "kv"
+func startTestVault(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/sys/health", func(w http.ResponseWriter, r *http.Request) {
+		// a sealed vault answers with 503, it is reachable nevertheless
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"initialized":true,"sealed":true}`))
+	})
+	mux.HandleFunc("/v1/auth/token/lookup-self", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"id":"test-token"}}`))
+	})
+	mux.HandleFunc("/v1/secret/data/app/db", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"data":{"user":"app","password":"s3cret","port":5432,"tls":{"enabled":true}},"metadata":{"version":3,"created_time":"2024-01-01T00:00:00Z","deletion_time":""}}}`))
+	})
+	mux.HandleFunc("/v1/kv/app/db", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"user":"app","password":"s3cret"}}`))
+	})
+	mux.HandleFunc("/v1/ns-only/data/app", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Namespace") != "team1" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"key":"value"},"metadata":{}}}`))
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != vaultTestToken {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		if _, pattern := mux.Handler(r); pattern == "" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVault_GetEntry(t *testing.T) {
+	server := startTestVault(t)
+	t.Setenv("VAULT_TOKEN", vaultTestToken)
+	tests := []struct {
+		name    string
+		vault   *Vault
+		key     string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "KV version 2",
+			vault: &Vault{Mount: "secret", KVVersion: 2},
+			key:   "app/db",
+			want: map[string]string{
+				"user":                   "app",
+				"password":               "s3cret",
+				"port":                   "5432",
+				"tls":                    `{"enabled":true}`,
+				"metadata:version":       "3",
+				"metadata:created_time":  "2024-01-01T00:00:00Z",
+				"metadata:deletion_time": "",
+			},
+		},
+		{
+			name:  "KV version 1",
+			vault: &Vault{Mount: "/kv/", KVVersion: 1},
+			key:   "/app/db",
+			want:  map[string]string{"user": "app", "password": "s3cret"},
+		},
+		{
+			name:  "Namespace",
+			vault: &Vault{Mount: "ns-only", KVVersion: 2, Namespace: "team1"},
+			key:   "app",
+			want:  map[string]string{"key": "value"},
+		},
+		{
+			name:    "Missing secret",
+			vault:   &Vault{Mount: "secret", KVVersion: 2},
+			key:     "app/missing",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid token",
+			vault:   &Vault{Mount: "secret", KVVersion: 2, TokenFile: filepath.Join(t.TempDir(), "missing")},
+			key:     "app/db",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.vault.Name = "vault"
+			tt.vault.Address = server.URL
+			got, err := tt.vault.GetEntry(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetEntry() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got.attributes, tt.want) {
+				t.Errorf("GetEntry() got = %v, want %v", got.attributes, tt.want)
+			}
+		})
+	}
+}
+
+func TestVault_Validate(t *testing.T) {
+	server := startTestVault(t)
+	t.Setenv("VAULT_TOKEN", "")
+	os.Unsetenv("VAULT_TOKEN")
+	dir := t.TempDir()
+	validTokenFile := filepath.Join(dir, "valid")
+	invalidTokenFile := filepath.Join(dir, "invalid")
+	if err := os.WriteFile(validTokenFile, []byte(vaultTestToken+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(invalidTokenFile, []byte("wrong"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	tests := []struct {
+		name    string
+		vault   *Vault
+		wantErr bool
+	}{
+		{name: "Valid", vault: &Vault{Address: server.URL, KVVersion: 2, TokenFile: validTokenFile}},
+		{name: "Invalid token", vault: &Vault{Address: server.URL, KVVersion: 2, TokenFile: invalidTokenFile}, wantErr: true},
+		{name: "Unreachable", vault: &Vault{Address: unreachable.URL, KVVersion: 2, TokenFile: validTokenFile}, wantErr: true},
+		{name: "Invalid KV version", vault: &Vault{Address: server.URL, KVVersion: 3, TokenFile: validTokenFile}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.vault.Name = "vault"
+			err, out := tt.vault.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v, out %v", err, tt.wantErr, out)
+			}
+		})
+	}
+}
+
+func TestCreateStorageAdapter_vault(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]string
+		wantErr bool
+	}{
+		// the server is not contacted when creating the storage adapter
+		{name: "Unreachable server", config: map[string]string{"address": "http://127.0.0.1:1", "mount": "secret"}},
+		{name: "Invalid KV version", config: map[string]string{"address": "http://127.0.0.1:1", "kvVersion": "3"}, wantErr: true},
+		{name: "Invalid address", config: map[string]string{"address": "not a url"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CreateStorageAdapter("vault", Storage{StorageType: VaultTypeIdentifier, Config: tt.config})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreateStorageAdapter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateStorageAdapter_vaultDefaultMount(t *testing.T) {
+	server := startTestVault(t)
+	t.Setenv("VAULT_TOKEN", vaultTestToken)
+	storage, err := CreateStorageAdapter("vault", Storage{
+		StorageType: VaultTypeIdentifier,
+		Config:      map[string]string{"address": server.URL},
+	})
+	if err != nil {
+		t.Fatalf("CreateStorageAdapter() error = %v", err)
+	}
+	got, err := storage.GetEntry("app/db")
+	if err != nil {
+		t.Fatalf("GetEntry() error = %v", err)
+	}
+	if user, _ := got.GetAttribute("user"); user == nil || *user != "app" {
+		t.Errorf("GetEntry() got = %v, want the secret of the mount secret", got.attributes)
+	}
+}
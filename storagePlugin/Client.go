@@ -0,0 +1,89 @@
+package storagePlugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Client calls a plugin executable
+type Client struct {
+	Executable string
+}
+
+// Call starts the plugin, sends the request and returns the response. Errors reported by the plugin are returned as
+// error together with the response, except for Response.Unsupported, which has to be checked by the caller. The
+// ProtocolVersion is set if the request does not contain one.
+func (c *Client) Call(request Request) (*Response, error) {
+	if request.ProtocolVersion == 0 {
+		request.ProtocolVersion = ProtocolVersion
+	}
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(c.Executable)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	// stderr is captured as the wrapper of envManager would evaluate it
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			return nil, fmt.Errorf("the plugin %s failed: %w", c.Executable, err)
+		}
+		return nil, fmt.Errorf("the plugin %s failed: %w: %s", c.Executable, err, message)
+	}
+	var response Response
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("the plugin %s answered with an invalid response: %w", c.Executable, err)
+	}
+	if response.Error != "" {
+		return &response, errors.New(response.Error)
+	}
+	return &response, nil
+}
+
+// FindPlugin returns the executable of the plugin for the storage type on the PATH
+func FindPlugin(storageType string) (string, error) {
+	if storageType == "" || strings.ContainsAny(storageType, `/\`) {
+		return "", fmt.Errorf("invalid storage type %q", storageType)
+	}
+	return exec.LookPath(ExecutablePrefix + storageType)
+}
+
+// FindPlugins returns the storage types of all plugins on the PATH with their executable. If the PATH contains a plugin
+// multiple times, the first one is used like the shell does.
+func FindPlugins() map[string]string {
+	plugins := map[string]string{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			storageType, isPlugin := strings.CutPrefix(file.Name(), ExecutablePrefix)
+			if !isPlugin || storageType == "" {
+				continue
+			}
+			if _, exists := plugins[storageType]; exists {
+				continue
+			}
+			executable := filepath.Join(dir, file.Name())
+			if info, err := os.Stat(executable); err != nil || info.IsDir() || info.Mode().Perm()&0111 == 0 {
+				continue
+			}
+			plugins[storageType] = executable
+		}
+	}
+	return plugins
+}
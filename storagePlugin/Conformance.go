@@ -0,0 +1,166 @@
+package storagePlugin
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ConformanceOptions configure the conformance tests of TestPlugin
+type ConformanceOptions struct {
+	//Name is the name of the storage sent to the plugin
+	Name string
+	//Config is a valid config of the storage
+	Config map[string]string
+	//Key is the path of an existing entry
+	Key string
+	//MissingKey is the path of an entry which does not exist, a path unlikely to exist is used if it is empty
+	MissingKey string
+}
+
+// ConformanceResult is the result of a single conformance test. Err is nil if the test passed.
+type ConformanceResult struct {
+	Name string
+	Err  error
+}
+
+// TestPlugin runs the conformance tests against the plugin executable, so plugin authors can check that their plugin
+// speaks the protocol as expected by envManager. Run it with envManager debug plugin or call it from the tests of
+// the plugin.
+func TestPlugin(executable string, options ConformanceOptions) []ConformanceResult {
+	client := &Client{Executable: executable}
+	if options.Name == "" {
+		options.Name = "conformance"
+	}
+	if options.MissingKey == "" {
+		options.MissingKey = "envManager-conformance/missing-entry"
+	}
+	request := func(method string) Request {
+		return Request{Method: method, Name: options.Name, Config: options.Config}
+	}
+
+	tests := []struct {
+		name string
+		test func() error
+	}{
+		{
+			name: "getDefaultConfig returns a config",
+			test: func() error {
+				response, err := client.Call(request(MethodGetDefaultConfig))
+				if err != nil {
+					return err
+				}
+				if response.Unsupported {
+					return errors.New("the method is required")
+				}
+				return nil
+			},
+		},
+		{
+			name: "isCaseSensitive answers",
+			test: func() error {
+				response, err := client.Call(request(MethodIsCaseSensitive))
+				if err == nil && response.Unsupported {
+					return errors.New("the method is required")
+				}
+				return err
+			},
+		},
+		{
+			name: "validate accepts the config",
+			test: func() error {
+				response, err := client.Call(request(MethodValidate))
+				if err != nil {
+					return err
+				}
+				if response.Unsupported {
+					return errors.New("the method is required")
+				}
+				if len(response.Messages) == 0 {
+					return errors.New("no messages for the user, tell them what was checked")
+				}
+				return nil
+			},
+		},
+		{
+			name: "getEntry returns the attributes of " + options.Key,
+			test: func() error {
+				if options.Key == "" {
+					return errors.New("no key of an existing entry given")
+				}
+				getEntry := request(MethodGetEntry)
+				getEntry.Key = options.Key
+				response, err := client.Call(getEntry)
+				if err != nil {
+					return err
+				}
+				if len(response.Attributes) == 0 {
+					return errors.New("the entry has no attributes")
+				}
+				if _, exists := response.Attributes[""]; exists {
+					return errors.New("the entry has an attribute with an empty name")
+				}
+				return nil
+			},
+		},
+		{
+			name: "getEntry fails for " + options.MissingKey,
+			test: func() error {
+				getEntry := request(MethodGetEntry)
+				getEntry.Key = options.MissingKey
+				response, err := client.Call(getEntry)
+				if err == nil {
+					return errors.New("no error for a missing entry")
+				}
+				if response == nil {
+					return fmt.Errorf("the error must be reported in the response: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			name: "listEntries is unsupported or lists entries",
+			test: func() error {
+				response, err := client.Call(request(MethodListEntries))
+				if err != nil || response.Unsupported {
+					return err
+				}
+				for _, entry := range response.Entries {
+					if entry == "" {
+						return errors.New("an entry has an empty path")
+					}
+				}
+				return nil
+			},
+		},
+		{
+			name: "unknown methods are unsupported",
+			test: func() error {
+				response, err := client.Call(request("envManagerConformanceUnknown"))
+				if err != nil {
+					return err
+				}
+				if !response.Unsupported {
+					return errors.New("the response does not set unsupported")
+				}
+				return nil
+			},
+		},
+		{
+			name: "unknown protocol versions are rejected",
+			test: func() error {
+				unknownVersion := request(MethodGetDefaultConfig)
+				unknownVersion.ProtocolVersion = ProtocolVersion + 1000
+				if _, err := client.Call(unknownVersion); err == nil {
+					return fmt.Errorf("protocol version %d was accepted", unknownVersion.ProtocolVersion)
+				}
+				return nil
+			},
+		},
+	}
+
+	results := make([]ConformanceResult, 0, len(tests))
+	for _, test := range tests {
+		results = append(results, ConformanceResult{Name: test.name, Err: test.test()})
+	}
+	return results
+}
@@ -0,0 +1,65 @@
+package storagePlugin_test
+
+import (
+	"envManager/internal"
+	"envManager/storagePlugin"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTestPlugin(t *testing.T) {
+	executable := internal.InstallTestPlugin(t, "conformance")
+	results := storagePlugin.TestPlugin(executable, storagePlugin.ConformanceOptions{
+		Config: map[string]string{"valid": "true"},
+		Key:    "entry1",
+	})
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("TestPlugin() %s failed: %v", result.Name, result.Err)
+		}
+	}
+}
+
+func TestTestPlugin_nonConforming(t *testing.T) {
+	// answers every request with an empty response
+	executable := filepath.Join(t.TempDir(), storagePlugin.ExecutablePrefix+"broken")
+	if err := os.WriteFile(executable, []byte("#!/bin/sh\ncat > /dev/null\necho '{}'\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	results := storagePlugin.TestPlugin(executable, storagePlugin.ConformanceOptions{Key: "entry1"})
+	failed := map[string]bool{}
+	for _, result := range results {
+		failed[result.Name] = result.Err != nil
+	}
+	want := map[string]bool{
+		"getDefaultConfig returns a config":                       false,
+		"isCaseSensitive answers":                                 false,
+		"validate accepts the config":                             true,
+		"getEntry returns the attributes of entry1":               true,
+		"getEntry fails for envManager-conformance/missing-entry": true,
+		"listEntries is unsupported or lists entries":             false,
+		"unknown methods are unsupported":                         true,
+		"unknown protocol versions are rejected":                  true,
+	}
+	for name, wantFailed := range want {
+		if failed[name] != wantFailed {
+			t.Errorf("TestPlugin() %s failed = %t, want %t", name, failed[name], wantFailed)
+		}
+	}
+}
+
+func TestFindPlugins(t *testing.T) {
+	executable := internal.InstallTestPlugin(t, "findme")
+	plugins := storagePlugin.FindPlugins()
+	if plugins["findme"] != executable {
+		t.Errorf("FindPlugins() got %v, want findme with %s", plugins, executable)
+	}
+	found, err := storagePlugin.FindPlugin("findme")
+	if err != nil || found != executable {
+		t.Errorf("FindPlugin() got %s, %v, want %s", found, err, executable)
+	}
+	if _, err := storagePlugin.FindPlugin("../findme"); err == nil {
+		t.Errorf("FindPlugin() accepted a path")
+	}
+}
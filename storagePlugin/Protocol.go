@@ -0,0 +1,52 @@
+// Package storagePlugin implements the protocol of storage adapter plugins. A plugin is an executable named
+// envManager-storage-<type> on the PATH, which makes the storage type <type> available in envManager. For every call
+// envManager starts the plugin, writes a single Request as JSON to its stdin and reads a single Response as JSON from
+// its stdout. Plugins written in Go can use Serve to implement the protocol.
+package storagePlugin
+
+// ProtocolVersion is the version of the protocol spoken by envManager
+const ProtocolVersion = 1
+
+// ExecutablePrefix is the prefix of the executables of plugins, followed by the storage type
+const ExecutablePrefix = "envManager-storage-"
+
+// methods of the protocol
+const (
+	//MethodGetEntry retrieves the entry Request.Key, the plugin answers with Response.Attributes
+	MethodGetEntry = "getEntry"
+	//MethodValidate validates Request.Config, the plugin answers with Response.Messages and Response.Error
+	MethodValidate = "validate"
+	//MethodGetDefaultConfig asks for the default config, the plugin answers with Response.Config
+	MethodGetDefaultConfig = "getDefaultConfig"
+	//MethodIsCaseSensitive asks if paths and attributes are case-sensitive, the plugin answers with
+	//Response.CaseSensitive
+	MethodIsCaseSensitive = "isCaseSensitive"
+	//MethodListEntries lists the entries starting with Request.Prefix, the plugin answers with Response.Entries. This
+	//method is optional, plugins not supporting it answer with Response.Unsupported.
+	MethodListEntries = "listEntries"
+)
+
+// Request is sent by envManager to the plugin
+type Request struct {
+	ProtocolVersion int    `json:"protocolVersion"`
+	Method          string `json:"method"`
+	//Name is the name of the storage in the config file
+	Name string `json:"name,omitempty"`
+	//Config is the config of the storage in the config file
+	Config map[string]string `json:"config,omitempty"`
+	Key    string            `json:"key,omitempty"`
+	Prefix string            `json:"prefix,omitempty"`
+}
+
+// Response is the answer of the plugin to a Request
+type Response struct {
+	//Error is set if the request failed
+	Error string `json:"error,omitempty"`
+	//Unsupported is set if the plugin does not implement the method
+	Unsupported   bool              `json:"unsupported,omitempty"`
+	Attributes    map[string]string `json:"attributes,omitempty"`
+	Messages      []string          `json:"messages,omitempty"`
+	Config        map[string]string `json:"config,omitempty"`
+	CaseSensitive bool              `json:"caseSensitive,omitempty"`
+	Entries       []string          `json:"entries,omitempty"`
+}
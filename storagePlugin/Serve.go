@@ -0,0 +1,96 @@
+package storagePlugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrUnsupported can be returned by optional methods of a Plugin, it is reported as Response.Unsupported
+var ErrUnsupported = errors.New("the method is not supported by the plugin")
+
+// Plugin is implemented by storage adapter plugins written in Go. The methods correspond to the methods of the
+// StorageAdapter interface of envManager, but receive the config of the storage, as every call starts the plugin again.
+type Plugin interface {
+	//GetEntry returns the attributes of the entry addressed by key
+	GetEntry(name string, config map[string]string, key string) (map[string]string, error)
+	//Validate verifies the config and returns information for the user
+	Validate(name string, config map[string]string) (error, []string)
+	//GetDefaultConfig returns the config written to the config file when adding a storage of this type
+	GetDefaultConfig() map[string]string
+	//IsCaseSensitive indicates if paths and attributes are case-sensitive
+	IsCaseSensitive() bool
+}
+
+// EntryLister is implemented by plugins supporting MethodListEntries
+type EntryLister interface {
+	//ListEntries returns the paths of the entries starting with prefix
+	ListEntries(name string, config map[string]string, prefix string) ([]string, error)
+}
+
+// Serve answers the request on stdin and exits. It is meant to be the only call in the main function of a plugin.
+func Serve(plugin Plugin) {
+	if err := ServeIO(plugin, os.Stdin, os.Stdout); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// ServeIO reads a single request from in and writes the response to out. Errors of the plugin are part of the
+// response, an error is only returned if the request could not be read or the response could not be written.
+func ServeIO(plugin Plugin, in io.Reader, out io.Writer) error {
+	var request Request
+	if err := json.NewDecoder(in).Decode(&request); err != nil {
+		return fmt.Errorf("failed to read the request: %w", err)
+	}
+	return json.NewEncoder(out).Encode(handleRequest(plugin, request))
+}
+
+// handleRequest calls the method of the plugin for the request
+func handleRequest(plugin Plugin, request Request) Response {
+	if request.ProtocolVersion != ProtocolVersion {
+		return Response{Error: fmt.Sprintf("unsupported protocol version %d, the plugin supports version %d", request.ProtocolVersion, ProtocolVersion)}
+	}
+	switch request.Method {
+	case MethodGetEntry:
+		attributes, err := plugin.GetEntry(request.Name, request.Config, request.Key)
+		if err != nil {
+			return errorResponse(err)
+		}
+		return Response{Attributes: attributes}
+	case MethodValidate:
+		err, messages := plugin.Validate(request.Name, request.Config)
+		response := Response{Messages: messages}
+		if err != nil {
+			response.Error = err.Error()
+		}
+		return response
+	case MethodGetDefaultConfig:
+		return Response{Config: plugin.GetDefaultConfig()}
+	case MethodIsCaseSensitive:
+		return Response{CaseSensitive: plugin.IsCaseSensitive()}
+	case MethodListEntries:
+		lister, isLister := plugin.(EntryLister)
+		if !isLister {
+			return Response{Unsupported: true}
+		}
+		entries, err := lister.ListEntries(request.Name, request.Config, request.Prefix)
+		if err != nil {
+			return errorResponse(err)
+		}
+		return Response{Entries: entries}
+	default:
+		return Response{Unsupported: true}
+	}
+}
+
+// errorResponse converts an error of the plugin into a response
+func errorResponse(err error) Response {
+	if errors.Is(err, ErrUnsupported) {
+		return Response{Unsupported: true}
+	}
+	return Response{Error: err.Error()}
+}
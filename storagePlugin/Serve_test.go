@@ -0,0 +1,109 @@
+package storagePlugin_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"envManager/internal"
+	"envManager/storagePlugin"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	internal.ServeTestPlugin()
+	os.Exit(m.Run())
+}
+
+// minimalPlugin implements only the required methods
+type minimalPlugin struct {
+	internal.TestPlugin
+}
+
+// ListEntries hides the method of the embedded TestPlugin
+func (p minimalPlugin) ListEntries() {}
+
+func TestServeIO(t *testing.T) {
+	tests := []struct {
+		name    string
+		plugin  storagePlugin.Plugin
+		request string
+		want    storagePlugin.Response
+		wantErr bool
+	}{
+		{
+			name:    "Get entry",
+			plugin:  internal.TestPlugin{},
+			request: `{"protocolVersion":1,"method":"getEntry","key":"entry1"}`,
+			want:    storagePlugin.Response{Attributes: map[string]string{"UserName": "user1", "Password": "pass1"}},
+		},
+		{
+			name:    "Missing entry",
+			plugin:  internal.TestPlugin{},
+			request: `{"protocolVersion":1,"method":"getEntry","key":"missing"}`,
+			want:    storagePlugin.Response{Error: "could not find entry missing"},
+		},
+		{
+			name:    "Validate",
+			plugin:  internal.TestPlugin{},
+			request: `{"protocolVersion":1,"method":"validate","config":{"valid":"false"}}`,
+			want:    storagePlugin.Response{Error: "the config is invalid", Messages: []string{"Option valid is false"}},
+		},
+		{
+			name:    "Default config",
+			plugin:  internal.TestPlugin{},
+			request: `{"protocolVersion":1,"method":"getDefaultConfig"}`,
+			want:    storagePlugin.Response{Config: map[string]string{"valid": "true"}},
+		},
+		{
+			name:    "List entries",
+			plugin:  internal.TestPlugin{},
+			request: `{"protocolVersion":1,"method":"listEntries","prefix":"group1/"}`,
+			want:    storagePlugin.Response{Entries: []string{"group1/g1e1"}},
+		},
+		{
+			name:    "List entries unsupported",
+			plugin:  minimalPlugin{},
+			request: `{"protocolVersion":1,"method":"listEntries"}`,
+			want:    storagePlugin.Response{Unsupported: true},
+		},
+		{
+			name:    "Unknown method",
+			plugin:  internal.TestPlugin{},
+			request: `{"protocolVersion":1,"method":"unknown"}`,
+			want:    storagePlugin.Response{Unsupported: true},
+		},
+		{
+			name:    "Unknown protocol version",
+			plugin:  internal.TestPlugin{},
+			request: `{"protocolVersion":2,"method":"getDefaultConfig"}`,
+			want:    storagePlugin.Response{Error: "unsupported protocol version 2, the plugin supports version 1"},
+		},
+		{
+			name:    "Invalid request",
+			plugin:  internal.TestPlugin{},
+			request: `no json`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := storagePlugin.ServeIO(tt.plugin, strings.NewReader(tt.request), &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ServeIO() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var got storagePlugin.Response
+			if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ServeIO() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
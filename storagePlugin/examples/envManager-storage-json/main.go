@@ -0,0 +1,80 @@
+// envManager-storage-json is the reference storage adapter plugin. It reads the entries from a JSON file like
+//
+//	{"path/of/entry": {"attribute": "value"}}
+//
+// Install it on your PATH and configure a storage with the type json and the path of the file.
+package main
+
+import (
+	"encoding/json"
+	"envManager/storagePlugin"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+type jsonPlugin struct{}
+
+func (p jsonPlugin) GetEntry(_ string, config map[string]string, key string) (map[string]string, error) {
+	entries, err := readEntries(config)
+	if err != nil {
+		return nil, err
+	}
+	entry, exists := entries[key]
+	if !exists {
+		return nil, fmt.Errorf("could not find entry %s in %s", key, config["path"])
+	}
+	return entry, nil
+}
+
+func (p jsonPlugin) Validate(name string, config map[string]string) (error, []string) {
+	_, err := readEntries(config)
+	out := []string{fmt.Sprintf("Configured file is %s\nFile is readable: %t", config["path"], err == nil)}
+	if err != nil {
+		return fmt.Errorf("validation of %s failed: %w", name, err), out
+	}
+	return nil, out
+}
+
+func (p jsonPlugin) GetDefaultConfig() map[string]string {
+	return map[string]string{
+		"path": "",
+	}
+}
+
+func (p jsonPlugin) IsCaseSensitive() bool {
+	return true
+}
+
+func (p jsonPlugin) ListEntries(_ string, config map[string]string, prefix string) ([]string, error) {
+	entries, err := readEntries(config)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for path := range entries {
+		if strings.HasPrefix(path, prefix) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// readEntries reads the entries from the file configured in path
+func readEntries(config map[string]string) (map[string]map[string]string, error) {
+	data, err := os.ReadFile(config["path"])
+	if err != nil {
+		return nil, err
+	}
+	var entries map[string]map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", config["path"], err)
+	}
+	return entries, nil
+}
+
+func main() {
+	storagePlugin.Serve(jsonPlugin{})
+}
@@ -0,0 +1,43 @@
+function envManager
+    # get the first parameter (= verb like load, unload or debug)
+    set -l verb $argv[1]
+
+    # these verbs run other programs which need the terminal, so the binary is called directly
+    switch $verb
+        case exec shell
+            envManager-bin $argv
+            return $status
+    end
+
+    # get a temporary file where the stderr will be stored. The file has mode 600 by default.
+    set -l TMPFILE (mktemp -t "XXXXXXXXXXXXXX")
+    # make envManager-bin write fish statements, $SHELL might point to another shell
+    set -lx ENVMANAGER_SHELL fish
+    # call the binary and redirect its stderr into TMPFILE. If the binary is not in your PATH, put an absolute path here.
+    envManager-bin $argv 2> $TMPFILE
+    # collect the exit code of the envManager-bin call
+    set -l exitCode $status
+    # read and destroy the TMPFILE, string collect keeps the newlines in the values
+    set -l tmpValue (cat $TMPFILE | string collect)
+    rm -f $TMPFILE
+
+    # write the error message to stderr if the binary returned a non-zero exit code
+    if test $exitCode -ne 0
+        printf '%s\n' $tmpValue 1>&2
+        return $exitCode
+    end
+
+    switch $verb
+        # the output of these verbs should be eval'ed
+        case load unload _hook agent
+            eval $tmpValue
+            return $exitCode
+        # the stderr output of the __complete verbs is to be discarded
+        case '__complete*'
+            return $exitCode
+        # for all other cases, just show what the binary returned in stderr
+        case '*'
+            printf '%s\n' $tmpValue 1>&2
+            return $exitCode
+    end
+end
@@ -4,6 +4,15 @@ function envManager() {
   local verb TMPFILE exitCode tmpValue
   # get the first parameter (= verb like load, unload or debug)
   verb=$1
+
+  # these verbs run other programs which need the terminal, so the binary is called directly
+  case "$verb" in
+    exec|shell)
+      envManager-bin "$@"
+      return $?
+      ;;
+  esac
+
   # get a temporary file where the stderr will be stored. The file has mode 600 by default.
   TMPFILE=$(mktemp -t "XXXXXXXXXXXXXX")
   # call the binary and redirect its stderr into TMPFILE. If the binary is not in your PATH, put an absolute path here.
@@ -21,7 +30,7 @@ function envManager() {
 
   case "$verb" in
     # the output of these verbs should be eval'ed
-    load|unload)
+    load|unload|_hook|agent)
       eval "$tmpValue"
       return $exitCode
       ;;